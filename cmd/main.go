@@ -4,18 +4,26 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/langchou/proxyPool/internal/api"
 	"github.com/langchou/proxyPool/internal/checker"
 	"github.com/langchou/proxyPool/internal/config"
 	"github.com/langchou/proxyPool/internal/crawler"
+	"github.com/langchou/proxyPool/internal/crawler/sources"
+	"github.com/langchou/proxyPool/internal/gateway"
+	"github.com/langchou/proxyPool/internal/geoip"
 	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/metrics"
 	"github.com/langchou/proxyPool/internal/middleware"
+	"github.com/langchou/proxyPool/internal/score"
 	"github.com/langchou/proxyPool/internal/storage"
 	"github.com/langchou/proxyPool/internal/validator"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -45,23 +53,116 @@ func main() {
 	gin.SetMode(config.GlobalConfig.Server.Mode)
 
 	// 初始化存储
-	store := storage.NewRedisStorage(
-		config.GlobalConfig.GetRedisAddr(),
-		config.GlobalConfig.Redis.Password,
-		config.GlobalConfig.Redis.DB,
-	)
-	logger.Log.Info("Redis storage initialized")
+	var store storage.Storage
+	var redisStore *storage.RedisStorage
+	switch config.GlobalConfig.Storage.Backend {
+	case "memory":
+		store = storage.NewMemoryStorage()
+		logger.Log.Info("Memory storage initialized")
+	case "bolt":
+		boltStore, err := storage.NewBoltStorage(config.GlobalConfig.Storage.Path)
+		if err != nil {
+			logger.Log.Fatal("Failed to open bolt storage", zap.Error(err))
+		}
+		defer boltStore.Close()
+		store = boltStore
+		logger.Log.Info("Bolt storage initialized", zap.String("path", config.GlobalConfig.Storage.Path))
+	default:
+		redisStore = storage.NewRedisStorage(
+			config.GlobalConfig.GetRedisAddr(),
+			config.GlobalConfig.Redis.Password,
+			config.GlobalConfig.Redis.DB,
+		)
+		store = redisStore
+		logger.Log.Info("Redis storage initialized")
+
+		// 迁移旧版本的数据结构
+		if migrated, err := redisStore.Migrate(context.Background()); err != nil {
+			logger.Log.Error("Failed to migrate legacy proxies", zap.Error(err))
+		} else if migrated > 0 {
+			logger.Log.Info("Migrated legacy proxies", zap.Int("count", migrated))
+		}
+	}
 
 	// 初始化验证器
-	validator := validator.NewValidator(config.GlobalConfig.GetValidatorTimeout())
-	logger.Log.Info("Proxy validator initialized")
+	var targets []validator.Target
+	if len(config.GlobalConfig.Validator.Targets) == 0 {
+		targets = append(targets, validator.DefaultTarget(config.GlobalConfig.Validator.TestURL))
+	}
+	for _, t := range config.GlobalConfig.Validator.Targets {
+		target, err := validator.NewTarget(t.Name, t.URL, t.ExpectedStatus, t.Contains, t.JSONField)
+		if err != nil {
+			logger.Log.Fatal("Invalid validation target", zap.Error(err))
+		}
+		targets = append(targets, target)
+	}
+	validator := validator.NewValidator(validator.Options{
+		Timeout:    config.GlobalConfig.GetValidatorTimeout(),
+		Targets:    targets,
+		JudgeURL:   config.GlobalConfig.GetJudgeURL(),
+		ConnectURL: config.GlobalConfig.Validator.ConnectURL,
+	})
+	logger.Log.Info("Proxy validator initialized", zap.Int("targets", len(targets)))
+
+	// 初始化评分器
+	scorer := score.NewScorer(
+		config.GlobalConfig.Score.Initial,
+		config.GlobalConfig.Score.Max,
+		config.GlobalConfig.Score.Min,
+		config.GlobalConfig.Score.Increment,
+		config.GlobalConfig.Score.Decrement,
+	)
+
+	// 初始化配置中声明的代理列表源
+	var listSources []sources.Source
+	for _, s := range config.GlobalConfig.Crawler.Sources {
+		source, err := sources.NewListSource(sources.ListConfig{
+			Name:      s.Name,
+			URL:       s.URL,
+			Path:      s.Path,
+			Format:    s.Format,
+			Type:      s.Type,
+			Anonymity: s.Anonymity,
+		})
+		if err != nil {
+			logger.Log.Fatal("Invalid crawler source", zap.String("name", s.Name), zap.Error(err))
+		}
+		listSources = append(listSources, source)
+	}
+
+	// 初始化离线 GeoIP 数据库（如果配置）
+	var geoReader *geoip.Reader
+	if config.GlobalConfig.GeoIP.CityDB != "" || config.GlobalConfig.GeoIP.ASNDB != "" {
+		var err error
+		geoReader, err = geoip.NewReader(config.GlobalConfig.GeoIP.CityDB, config.GlobalConfig.GeoIP.ASNDB)
+		if err != nil {
+			logger.Log.Fatal("Failed to open GeoIP database", zap.Error(err))
+		}
+		defer geoReader.Close()
+		logger.Log.Info("GeoIP database loaded",
+			zap.String("city_db", config.GlobalConfig.GeoIP.CityDB),
+			zap.String("asn_db", config.GlobalConfig.GeoIP.ASNDB))
+	}
 
 	// 初始化爬虫管理器
-	crawler := crawler.NewManager(store, validator)
-	logger.Log.Info("Crawler manager initialized")
+	crawler := crawler.NewManager(store, validator, scorer, crawler.Options{
+		BatchSize:  config.GlobalConfig.Crawler.BatchSize,
+		Workers:    config.GlobalConfig.Crawler.Workers,
+		FetchDelay: config.GlobalConfig.GetFetchDelay(),
+		MaxRetry:   config.GlobalConfig.Crawler.MaxRetry,
+		Sources:    listSources,
+		GeoIP:      geoReader,
+	})
+	logger.Log.Info("Crawler manager initialized", zap.Int("list_sources", len(listSources)))
 
 	// 初始化检查器
-	checker := checker.NewChecker(store, validator)
+	checker := checker.NewChecker(
+		store,
+		validator,
+		scorer,
+		config.GlobalConfig.Validator.Concurrency,
+		config.GlobalConfig.GetCheckTimeout(),
+	)
 	logger.Log.Info("Proxy checker initialized")
 
 	// 启动后台爬虫任务
@@ -127,32 +228,113 @@ func main() {
 		}
 	}()
 
+	// 启动转发代理网关（如果启用）
+	if config.GlobalConfig.Gateway.HTTPEnabled {
+		go func() {
+			addr := fmt.Sprintf(":%d", config.GlobalConfig.Gateway.HTTPPort)
+			logger.Log.Info("Starting HTTP proxy gateway", zap.String("addr", addr))
+
+			server := &http.Server{
+				Addr: addr,
+				Handler: gateway.NewHTTPProxy(
+					store,
+					config.GlobalConfig.GetGatewayTimeout(),
+					config.GlobalConfig.Gateway.MaxRetry,
+					config.GlobalConfig.GetSessionTTL(),
+				),
+			}
+			if err := server.ListenAndServe(); err != nil {
+				logger.Log.Error("HTTP proxy gateway stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	// 启动 SOCKS5 代理服务（如果启用）
+	if config.GlobalConfig.Gateway.SOCKS5Enabled {
+		go func() {
+			addr := fmt.Sprintf(":%d", config.GlobalConfig.Gateway.SOCKS5Port)
+			logger.Log.Info("Starting SOCKS5 gateway", zap.String("addr", addr))
+
+			server := gateway.NewSOCKS5Server(
+				store,
+				config.GlobalConfig.GetGatewayTimeout(),
+				config.GlobalConfig.Gateway.MaxRetry,
+				config.GlobalConfig.GetSessionTTL(),
+			)
+			if err := server.ListenAndServe(addr); err != nil {
+				logger.Log.Error("SOCKS5 gateway stopped", zap.Error(err))
+			}
+		}()
+	}
+
 	// 启动API服务
 	r := gin.New()
 	r.Use(middleware.Logger())
+	r.Use(middleware.Metrics())
 	r.Use(middleware.ErrorHandler())
+	r.Use(gin.Recovery())
+
+	// 代理判定接口（如果启用），代理验证时通过代理访问，不经过认证和限流
+	if config.GlobalConfig.Judge.Enabled {
+		r.GET("/judge", api.Judge)
+		logger.Log.Info("Proxy judge endpoint enabled", zap.String("judge_url", config.GlobalConfig.GetJudgeURL()))
+	}
+
+	// Prometheus 指标接口（如果启用），代理池大小在采集时从存储中统计
+	if config.GlobalConfig.Metrics.Enabled {
+		prometheus.MustRegister(metrics.NewPoolCollector(store))
+		r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+		logger.Log.Info("Metrics endpoint enabled")
+	}
+
+	// 管理接口（配置了管理员密钥时启用），使用单独的管理员密钥认证
+	if config.GlobalConfig.Security.AdminKey != "" {
+		adminHandler := api.NewAdminHandler(store, validator, scorer, config.GlobalConfig.Validator.Concurrency)
+		adminGroup := r.Group("/admin", middleware.AdminAuth())
+		adminGroup.POST("/proxies", adminHandler.AddProxy)
+		adminGroup.POST("/proxies/import", adminHandler.ImportProxies)
+		adminGroup.DELETE("/proxies/:addr", adminHandler.DeleteProxy)
+		adminGroup.POST("/proxies/:addr/check", adminHandler.CheckProxy)
+		logger.Log.Info("Admin endpoints enabled")
+	}
+
+	apiGroup := r.Group("/")
 
 	// 初始化限流器（如果启用）
 	if config.GlobalConfig.Security.RateLimitEnabled {
-		rateLimiter := middleware.NewRateLimiter(
-			store.GetRedisClient(),
-			config.GlobalConfig.Security.RateLimit,
-			time.Duration(config.GlobalConfig.Security.RateWindow)*time.Minute,
-			time.Duration(config.GlobalConfig.Security.BanDuration)*time.Hour,
-		)
-		r.Use(rateLimiter.RateLimit())
+		var rateLimiter *middleware.RateLimiter
+		if redisStore != nil {
+			rateLimiter = middleware.NewRateLimiter(
+				redisStore.GetRedisClient(),
+				config.GlobalConfig.Security.RateLimit,
+				time.Duration(config.GlobalConfig.Security.RateWindow)*time.Minute,
+				time.Duration(config.GlobalConfig.Security.BanDuration)*time.Hour,
+			)
+		} else {
+			// 没有 Redis 时使用进程内计数
+			rateLimiter = middleware.NewMemoryRateLimiter(
+				config.GlobalConfig.Security.RateLimit,
+				time.Duration(config.GlobalConfig.Security.RateWindow)*time.Minute,
+				time.Duration(config.GlobalConfig.Security.BanDuration)*time.Hour,
+			)
+		}
+		apiGroup.Use(rateLimiter.RateLimit())
 	}
 
-	r.Use(middleware.BasicAuth())  // 基本认证
-	r.Use(middleware.APIKeyAuth()) // API Key 认证
-	r.Use(gin.Recovery())
+	apiGroup.Use(middleware.BasicAuth())  // 基本认证
+	apiGroup.Use(middleware.APIKeyAuth()) // API Key 认证
 
 	handler := api.NewHandler(store)
-	r.GET("/proxy", handler.GetProxy)
-	r.GET("/proxies", handler.GetAllProxies)
+	apiGroup.GET("/proxy", handler.GetProxy)
+	apiGroup.GET("/proxies", handler.GetAllProxies)
+	apiGroup.GET("/proxies/:addr/history", handler.GetProxyHistory)
+
+	statsHandler := api.NewStatsHandler(store, crawler, checker)
+	apiGroup.GET("/stats", statsHandler.GetStats)
+	apiGroup.GET("/stats/sources", statsHandler.GetSourceStats)
 
 	// 添加健康检查接口
-	r.GET("/health", func(c *gin.Context) {
+	apiGroup.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status": "ok",
 			"time":   time.Now().Format(time.RFC3339),
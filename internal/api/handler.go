@@ -3,13 +3,14 @@ package api
 import (
 	"github.com/langchou/proxyPool/internal/api/response"
 	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/middleware"
 	"github.com/langchou/proxyPool/internal/model"
 	"github.com/langchou/proxyPool/internal/storage"
+	"net"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
@@ -24,19 +25,42 @@ func NewHandler(storage storage.Storage) *Handler {
 // GetProxy 获取代理
 // @param type: 代理类型，可选值：http,https,socks4,socks5，多个类型用逗号分隔
 // @param count: 返回数量，默认1
-// @param anonymous: 是否只返回高匿代理，可选值：true/false
+// @param anonymity: 匿名级别，可选值：transparent,anonymous,elite，多个级别用逗号分隔
+// @param anonymous: 是否只返回高匿代理，可选值：true/false，等同于 anonymity=elite
+// @param strategy: 选择策略，可选值：random,fastest,best,weighted,round_robin，默认random
+// @param target: 只返回通过该验证目标的代理
+// @param country: 出口国家代码，例如 DE，多个国家用逗号分隔
+// @param exclude_country: 排除的出口国家代码，多个国家用逗号分隔
+// @param asn: 出口 IP 所属自治系统号，例如 AS3320 或 3320，多个用逗号分隔
+// @param source: 代理来源的代理源名称，例如 kuaidaili，多个用逗号分隔
 func (h *Handler) GetProxy(c *gin.Context) {
 	logger.Log.Info("Received request for proxy")
 
 	// 解析请求参数
-	proxyTypes := parseProxyTypes(c.Query("type"))
 	count := parseCount(c.Query("count"), 1)
-	anonymous := c.Query("anonymous") == "true"
+	strategy := c.DefaultQuery("strategy", StrategyRandom)
+	if !isValidStrategy(strategy) {
+		response.BadRequest(c, "Invalid strategy")
+		return
+	}
 
-	// 获取所有代理
-	proxies, err := h.storage.GetAll(c.Request.Context())
+	// 按条件查询代理，随机和需要排序的策略直接在存储中截取前 count 个
+	filter := parseFilter(c)
+	switch strategy {
+	case StrategyRandom:
+		filter.OrderBy = storage.OrderByRandom
+		filter.Limit = count
+	case StrategyFastest:
+		filter.OrderBy = storage.OrderBySpeed
+		filter.Limit = count
+	case StrategyBest:
+		filter.OrderBy = storage.OrderByScore
+		filter.Limit = count
+	}
+
+	filtered, err := h.storage.Query(c.Request.Context(), filter)
 	if err != nil {
-		if err == redis.Nil {
+		if err == storage.ErrNotFound {
 			logger.Log.Warn("No proxies available")
 			response.Success(c, []response.ProxyData{})
 			return
@@ -46,47 +70,139 @@ func (h *Handler) GetProxy(c *gin.Context) {
 		return
 	}
 
-	// 过滤代理
-	filtered := filterProxies(proxies, proxyTypes, anonymous)
 	if len(filtered) == 0 {
 		response.Success(c, []response.ProxyData{})
 		return
 	}
 
-	// 限制返回数量
-	if count > len(filtered) {
-		count = len(filtered)
+	// 按策略选择代理
+	result, err := h.selectProxies(c.Request.Context(), filtered, strategy, count)
+	if err != nil {
+		logger.Log.Error("Failed to select proxies",
+			zap.String("strategy", strategy),
+			zap.Error(err))
+		response.Error(c, "Failed to select proxies")
+		return
 	}
 
-	result := filtered[:count]
 	logger.Log.Info("Successfully returned proxies",
+		zap.String("strategy", strategy),
 		zap.Int("requested", count),
 		zap.Int("returned", len(result)))
 
-	response.Success(c, response.ConvertProxies(result))
+	response.Success(c, convertProxies(c, result))
 }
 
 func (h *Handler) GetAllProxies(c *gin.Context) {
 	logger.Log.Info("Received request for all proxies")
 
-	// 解析请求参数
-	proxyTypes := parseProxyTypes(c.Query("type"))
-	anonymous := c.Query("anonymous") == "true"
-
-	proxies, err := h.storage.GetAll(c.Request.Context())
+	filtered, err := h.storage.Query(c.Request.Context(), parseFilter(c))
 	if err != nil {
 		logger.Log.Error("Failed to get all proxies", zap.Error(err))
 		response.Error(c, "Failed to get proxies")
 		return
 	}
 
-	// 过滤代理
-	filtered := filterProxies(proxies, proxyTypes, anonymous)
-
 	logger.Log.Info("Successfully returned all proxies",
 		zap.Int("total", len(filtered)))
 
-	response.Success(c, response.ConvertProxies(filtered))
+	response.Success(c, convertProxies(c, filtered))
+}
+
+// GetProxyHistory 获取单个代理的验证历史和可靠性统计
+// @param addr: 代理地址，格式为 ip:port
+func (h *Handler) GetProxyHistory(c *gin.Context) {
+	addr := c.Param("addr")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		response.BadRequest(c, "Invalid proxy address, expected ip:port")
+		return
+	}
+
+	proxy, err := h.storage.Get(c.Request.Context(), addr)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			response.NotFound(c, "Proxy not found")
+			return
+		}
+		logger.Log.Error("Failed to get proxy", zap.String("addr", addr), zap.Error(err))
+		response.Error(c, "Failed to get proxy")
+		return
+	}
+
+	data := response.ConvertHistory(proxy)
+	if !middleware.IsAdmin(c) {
+		data.MaskCredentials()
+	}
+	response.Success(c, data)
+}
+
+// convertProxies 转换代理列表，未携带管理员密钥时隐藏代理账号密码
+func convertProxies(c *gin.Context, proxies []*model.Proxy) []response.ProxyData {
+	result := response.ConvertProxies(proxies)
+	if !middleware.IsAdmin(c) {
+		for i := range result {
+			result[i].MaskCredentials()
+		}
+	}
+	return result
+}
+
+// 解析查询条件，GetProxy 和 GetAllProxies 共用
+func parseFilter(c *gin.Context) storage.Filter {
+	return storage.Filter{
+		Types:     parseProxyTypes(c.Query("type")),
+		Anonymity: parseAnonymity(c),
+		Target:    c.Query("target"),
+		Countries: parseCountries(c.Query("country")),
+		Excluded:  parseCountries(c.Query("exclude_country")),
+		ASNs:      parseASNs(c.Query("asn")),
+		Sources:   parseList(c.Query("source")),
+	}
+}
+
+// 解析逗号分隔的列表，忽略空值
+func parseList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// 解析国家代码列表
+func parseCountries(countryStr string) []string {
+	if countryStr == "" {
+		return nil
+	}
+
+	var result []string
+	for _, country := range strings.Split(countryStr, ",") {
+		if country = model.NormalizeCountry(country); country != "" {
+			result = append(result, country)
+		}
+	}
+	return result
+}
+
+// 解析自治系统号列表
+func parseASNs(asnStr string) []uint {
+	if asnStr == "" {
+		return nil
+	}
+
+	var result []uint
+	for _, s := range strings.Split(asnStr, ",") {
+		if asn := model.ParseASN(s); asn != 0 {
+			result = append(result, asn)
+		}
+	}
+	return result
 }
 
 // 解析代理类型
@@ -108,6 +224,22 @@ func parseProxyTypes(typeStr string) []model.ProxyType {
 	return result
 }
 
+// 解析匿名级别，anonymous=true 等同于 anonymity=elite
+func parseAnonymity(c *gin.Context) []model.AnonymityLevel {
+	var result []model.AnonymityLevel
+	if levels := c.Query("anonymity"); levels != "" {
+		for _, l := range strings.Split(levels, ",") {
+			if level := model.ParseAnonymityLevel(l); level.IsValid() {
+				result = append(result, level)
+			}
+		}
+	}
+	if c.Query("anonymous") == "true" {
+		result = append(result, model.AnonymityElite)
+	}
+	return result
+}
+
 // 解析数量
 func parseCount(countStr string, defaultValue int) int {
 	if countStr == "" {
@@ -120,36 +252,3 @@ func parseCount(countStr string, defaultValue int) int {
 	}
 	return count
 }
-
-// 过滤代理
-func filterProxies(proxies []*model.Proxy, types []model.ProxyType, anonymous bool) []*model.Proxy {
-	if len(proxies) == 0 {
-		return proxies
-	}
-
-	filtered := make([]*model.Proxy, 0, len(proxies))
-	for _, proxy := range proxies {
-		// 类型过滤
-		if len(types) > 0 {
-			typeMatched := false
-			for _, t := range types {
-				if proxy.Type == t {
-					typeMatched = true
-					break
-				}
-			}
-			if !typeMatched {
-				continue
-			}
-		}
-
-		// 匿名性过滤
-		if anonymous && !proxy.Anonymous {
-			continue
-		}
-
-		filtered = append(filtered, proxy)
-	}
-
-	return filtered
-}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Judge 代理判定接口，返回请求的来源地址和收到的全部请求头，格式与 httpbin 的 /get 兼容
+// 来源地址直接取自 TCP 连接，不信任 X-Forwarded-For 等请求头，验证器据此判断代理的出口 IP 和匿名级别
+func Judge(c *gin.Context) {
+	origin, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		origin = c.Request.RemoteAddr
+	}
+
+	headers := make(map[string]string, len(c.Request.Header)+1)
+	for name, values := range c.Request.Header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	headers["Host"] = c.Request.Host
+
+	c.JSON(http.StatusOK, gin.H{
+		"origin":  origin,
+		"headers": headers,
+	})
+}
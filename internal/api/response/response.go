@@ -2,6 +2,8 @@ package response
 
 import (
 	"net/http"
+	"time"
+
 	"github.com/langchou/proxyPool/internal/model"
 
 	"github.com/gin-gonic/gin"
@@ -10,10 +12,14 @@ import (
 // 响应码定义
 const (
 	CodeSuccess       = 200
+	CodeBadRequest    = 400
 	CodeNotFound      = 404
 	CodeInternalError = 500
 )
 
+// credentialMask 代理账号密码的掩码
+const credentialMask = "******"
+
 // Response 通用响应结构
 type Response struct {
 	Code    int         `json:"code"`           // 响应码
@@ -23,12 +29,37 @@ type Response struct {
 
 // ProxyData 代理数据结构
 type ProxyData struct {
-	IP        string `json:"ip"`        // IP地址
-	Port      string `json:"port"`      // 端口
-	Type      string `json:"type"`      // 代理类型
-	Anonymous bool   `json:"anonymous"` // 是否高匿
-	Speed     int64  `json:"speed_ms"`  // 响应速度（毫秒）
-	Score     int    `json:"score"`     // 可用性评分
+	IP        string    `json:"ip"`                 // IP地址
+	Port      string    `json:"port"`               // 端口
+	Type      string    `json:"type"`               // 代理类型
+	Anonymity string    `json:"anonymity"`          // 匿名级别：transparent/anonymous/elite，未检测时为空
+	Anonymous bool      `json:"anonymous"`          // 是否高匿（匿名级别为 elite）
+	Speed     int64     `json:"speed_ms"`           // 响应速度（毫秒）
+	Score     int       `json:"score"`              // 可用性评分
+	Targets   []string  `json:"targets"`            // 通过的验证目标
+	ExitIP    string    `json:"exit_ip"`            // 出口 IP
+	Country   string    `json:"country"`            // 出口国家代码
+	Region    string    `json:"region"`             // 出口省/州
+	City      string    `json:"city"`               // 出口城市
+	ASN       uint      `json:"asn"`                // 出口 IP 所属自治系统号
+	Org       string    `json:"org"`                // 出口 IP 所属组织
+	Username  string    `json:"username,omitempty"` // 代理账号，未携带管理员密钥时打码
+	Password  string    `json:"password,omitempty"` // 代理密码，未携带管理员密钥时打码
+	Source    string    `json:"source"`             // 最先发现该代理的代理源，管理接口添加的代理为 admin
+	FirstSeen time.Time `json:"first_seen"`         // 加入代理池的时间
+
+	SupportsHTTP    bool     `json:"supports_http"`       // 是否支持普通 HTTP 转发
+	SupportsConnect bool     `json:"supports_connect"`    // 是否支持 CONNECT 隧道
+	Protocols       []string `json:"protocols,omitempty"` // 协议探测识别出的全部协议，仅类型未知的代理有
+
+	model.HistoryStats // 验证历史统计
+}
+
+// HistoryData 代理验证历史数据结构
+type HistoryData struct {
+	ProxyData
+	LastCheck time.Time           `json:"last_check"` // 最近一次验证通过的时间
+	History   []model.CheckRecord `json:"history"`    // 验证记录，按时间从旧到新
 }
 
 // Success 成功响应
@@ -40,6 +71,14 @@ func Success(c *gin.Context, data interface{}) {
 	})
 }
 
+// BadRequest 请求参数错误响应
+func BadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, Response{
+		Code:    CodeBadRequest,
+		Message: message,
+	})
+}
+
 // NotFound 未找到响应
 func NotFound(c *gin.Context, message string) {
 	c.JSON(http.StatusNotFound, Response{
@@ -58,13 +97,62 @@ func Error(c *gin.Context, message string) {
 
 // ConvertProxy 转换代理模型为响应数据
 func ConvertProxy(proxy *model.Proxy) ProxyData {
+	targets := proxy.Targets
+	if targets == nil {
+		targets = []string{}
+	}
+	var protocols []string
+	for _, t := range proxy.Protocols {
+		protocols = append(protocols, string(t))
+	}
 	return ProxyData{
 		IP:        proxy.IP,
 		Port:      proxy.Port,
 		Type:      string(proxy.Type),
-		Anonymous: proxy.Anonymous,
+		Anonymity: string(proxy.Anonymity),
+		Anonymous: proxy.Anonymity == model.AnonymityElite,
 		Speed:     proxy.Speed,
 		Score:     proxy.Score,
+		Targets:   targets,
+		ExitIP:    proxy.ExitIP,
+		Country:   proxy.Country,
+		Region:    proxy.Region,
+		City:      proxy.City,
+		ASN:       proxy.ASN,
+		Org:       proxy.Org,
+		Username:  proxy.Username,
+		Password:  proxy.Password,
+		Source:    proxy.Source,
+		FirstSeen: proxy.FirstSeen,
+
+		SupportsHTTP:    proxy.SupportsHTTP,
+		SupportsConnect: proxy.SupportsConnect,
+		Protocols:       protocols,
+
+		HistoryStats: proxy.Stats(),
+	}
+}
+
+// MaskCredentials 将代理账号密码替换为掩码，只保留账号的首字符
+func (d *ProxyData) MaskCredentials() {
+	if d.Username != "" {
+		d.Username = string([]rune(d.Username)[:1]) + credentialMask
+	}
+	if d.Password != "" {
+		d.Password = credentialMask
+	}
+}
+
+// ConvertHistory 转换代理的验证历史
+func ConvertHistory(proxy *model.Proxy) HistoryData {
+	history := proxy.History
+	if history == nil {
+		history = []model.CheckRecord{}
+	}
+	return HistoryData{
+		ProxyData: ConvertProxy(proxy),
+		LastCheck: proxy.LastCheck,
+		History:   history,
 	}
 }
 
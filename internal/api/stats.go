@@ -0,0 +1,62 @@
+package api
+
+import (
+	"github.com/langchou/proxyPool/internal/api/response"
+	"github.com/langchou/proxyPool/internal/checker"
+	"github.com/langchou/proxyPool/internal/crawler"
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StatsHandler 代理池统计接口
+type StatsHandler struct {
+	storage storage.Storage
+	crawler *crawler.Manager
+	checker *checker.Checker
+}
+
+func NewStatsHandler(storage storage.Storage, crawler *crawler.Manager, checker *checker.Checker) *StatsHandler {
+	return &StatsHandler{
+		storage: storage,
+		crawler: crawler,
+		checker: checker,
+	}
+}
+
+// StatsData 代理池统计数据
+type StatsData struct {
+	*storage.PoolStats
+	LastCrawl *crawler.RunStats `json:"last_crawl"` // 最近一次爬取的统计，包括各代理源的产出，服务启动后尚未完成爬取时为 null
+	LastCheck *checker.RunStats `json:"last_check"` // 最近一次定时检查的统计，尚未完成检查时为 null
+}
+
+// GetStats 获取代理池统计：按类型、匿名级别、评分区间的数量，响应速度分布，以及最近一次爬取和检查的结果
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	stats, err := h.storage.Stats(c.Request.Context())
+	if err != nil {
+		logger.Log.Error("Failed to get pool stats", zap.Error(err))
+		response.Error(c, "Failed to get stats")
+		return
+	}
+
+	response.Success(c, StatsData{
+		PoolStats: stats,
+		LastCrawl: h.crawler.LastRun(),
+		LastCheck: h.checker.LastRun(),
+	})
+}
+
+// GetSourceStats 获取各代理源最近 24 小时的抓取数、验证通过数、新加入代理数，以及加入 1 小时和 24 小时后的存活率
+func (h *StatsHandler) GetSourceStats(c *gin.Context) {
+	stats, err := h.storage.SourceStats(c.Request.Context())
+	if err != nil {
+		logger.Log.Error("Failed to get source stats", zap.Error(err))
+		response.Error(c, "Failed to get source stats")
+		return
+	}
+
+	response.Success(c, stats)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// 代理选择策略
+const (
+	StrategyRandom     = "random"      // 随机选择
+	StrategyFastest    = "fastest"     // 响应速度最快优先
+	StrategyBest       = "best"        // 评分最高优先
+	StrategyWeighted   = "weighted"    // 按评分加权随机
+	StrategyRoundRobin = "round_robin" // 轮询
+)
+
+// roundRobinCursor 轮询策略在存储中使用的游标名称
+const roundRobinCursor = "round_robin"
+
+// isValidStrategy 检查选择策略是否有效
+func isValidStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyRandom, StrategyFastest, StrategyBest, StrategyWeighted, StrategyRoundRobin:
+		return true
+	default:
+		return false
+	}
+}
+
+// selectProxies 按策略从候选代理中选出 count 个
+func (h *Handler) selectProxies(ctx context.Context, proxies []*model.Proxy, strategy string, count int) ([]*model.Proxy, error) {
+	if count > len(proxies) {
+		count = len(proxies)
+	}
+
+	// 复制一份，避免修改调用方的切片
+	candidates := make([]*model.Proxy, len(proxies))
+	copy(candidates, proxies)
+
+	switch strategy {
+	case StrategyFastest:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return fasterThan(candidates[i], candidates[j])
+		})
+	case StrategyBest:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Score != candidates[j].Score {
+				return candidates[i].Score > candidates[j].Score
+			}
+			return fasterThan(candidates[i], candidates[j])
+		})
+	case StrategyWeighted:
+		return weightedSample(candidates, count), nil
+	case StrategyRoundRobin:
+		return h.roundRobin(ctx, candidates, count)
+	default:
+		rand.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+	}
+
+	return candidates[:count], nil
+}
+
+// fasterThan 比较响应速度，未测速（速度为 0）的代理排在最后
+func fasterThan(a, b *model.Proxy) bool {
+	if a.Speed <= 0 {
+		return false
+	}
+	if b.Speed <= 0 {
+		return true
+	}
+	return a.Speed < b.Speed
+}
+
+// weightedSample 按评分加权不放回抽样（Efraimidis-Spirakis 算法）
+func weightedSample(candidates []*model.Proxy, count int) []*model.Proxy {
+	keys := make(map[*model.Proxy]float64, len(candidates))
+	for _, p := range candidates {
+		weight := float64(p.Score)
+		if weight < 1 {
+			weight = 1
+		}
+		keys[p] = math.Pow(rand.Float64(), 1/weight)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return keys[candidates[i]] > keys[candidates[j]]
+	})
+	return candidates[:count]
+}
+
+// roundRobin 按固定顺序轮询选择，游标保存在存储中以便多实例共享
+func (h *Handler) roundRobin(ctx context.Context, candidates []*model.Proxy, count int) ([]*model.Proxy, error) {
+	if count == 0 {
+		return candidates, nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].IP+":"+candidates[i].Port < candidates[j].IP+":"+candidates[j].Port
+	})
+
+	cursor, err := h.storage.IncrCursor(ctx, roundRobinCursor, int64(count))
+	if err != nil {
+		return nil, err
+	}
+
+	n := int64(len(candidates))
+	start := ((cursor-int64(count))%n + n) % n
+
+	result := make([]*model.Proxy, count)
+	for i := range result {
+		result[i] = candidates[(start+int64(i))%n]
+	}
+	return result, nil
+}
@@ -3,27 +3,82 @@ package checker
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/langchou/proxyPool/internal/config"
 	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/metrics"
+	"github.com/langchou/proxyPool/internal/model"
+	"github.com/langchou/proxyPool/internal/score"
 	"github.com/langchou/proxyPool/internal/storage"
 	"github.com/langchou/proxyPool/internal/validator"
 	"go.uber.org/zap"
 )
 
+// progressInterval 检查过程中输出进度日志的间隔
+const progressInterval = 10 * time.Second
+
 type Checker struct {
-	storage   storage.Storage
-	validator *validator.Validator
+	storage     storage.Storage
+	validator   *validator.Validator
+	scorer      *score.Scorer
+	concurrency int                      // 并发验证的协程数
+	timeout     time.Duration            // 单次检查的最长耗时，0 表示不限制
+	running     atomic.Bool              // 是否有检查正在进行
+	lastRun     atomic.Pointer[RunStats] // 最近一次完成的检查统计
+}
+
+// checkStats 单次检查的进度统计
+type checkStats struct {
+	checked atomic.Int64
+	passed  atomic.Int64
+	failed  atomic.Int64
+	removed atomic.Int64
+}
+
+// RunStats 一次检查的结果统计
+type RunStats struct {
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Total       int       `json:"total"`       // 开始检查时存储中的代理数
+	Checked     int64     `json:"checked"`     // 已检查的代理数
+	Passed      int64     `json:"passed"`      // 验证通过的代理数
+	Failed      int64     `json:"failed"`      // 验证失败但分数未到淘汰线的代理数
+	Removed     int64     `json:"removed"`     // 验证失败被删除的代理数
+	Interrupted bool      `json:"interrupted"` // 是否因超时等原因未检查完全部代理
 }
 
-func NewChecker(storage storage.Storage, validator *validator.Validator) *Checker {
+func NewChecker(storage storage.Storage, validator *validator.Validator, scorer *score.Scorer, concurrency int, timeout time.Duration) *Checker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
 	return &Checker{
-		storage:   storage,
-		validator: validator,
+		storage:     storage,
+		validator:   validator,
+		scorer:      scorer,
+		concurrency: concurrency,
+		timeout:     timeout,
 	}
 }
 
+// Run 并发检查所有已存储的代理，上一次检查未结束时跳过本次检查
 func (c *Checker) Run(ctx context.Context) error {
+	if !c.running.CompareAndSwap(false, true) {
+		logger.Log.Warn("Previous proxy check still in progress, skipping")
+		return nil
+	}
+	defer c.running.Store(false)
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
 	logger.Log.Info("Starting to check existing proxies")
+	start := time.Now()
 
 	// 从存储中获取所有代理
 	proxies, err := c.storage.GetAll(ctx)
@@ -31,48 +86,143 @@ func (c *Checker) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to get proxies from storage: %w", err)
 	}
 
-	logger.Log.Info("Retrieved proxies for checking", zap.Int("count", len(proxies)))
+	logger.Log.Info("Retrieved proxies for checking",
+		zap.Int("count", len(proxies)),
+		zap.Int("concurrency", c.concurrency))
+
+	var stats checkStats
+	jobs := make(chan *model.Proxy)
+	var wg sync.WaitGroup
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 已经开始的验证即使本次检查超时也要保存结果
+			storeCtx := context.WithoutCancel(ctx)
+			for proxy := range jobs {
+				c.checkProxy(storeCtx, proxy, &stats)
+			}
+		}()
+	}
+
+	// 定时输出检查进度
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				logger.Log.Info("Proxy check progress",
+					zap.Int64("checked", stats.checked.Load()),
+					zap.Int("total", len(proxies)),
+					zap.Int64("passed", stats.passed.Load()),
+					zap.Int64("removed", stats.removed.Load()))
+			}
+		}
+	}()
 
+feed:
 	for _, proxy := range proxies {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			logger.Log.Debug("Checking proxy",
-				zap.String("ip", proxy.IP),
-				zap.String("port", proxy.Port))
-
-			// 验证代理
-			valid, speed := c.validator.Validate(proxy)
-			if valid {
-				proxy.Speed = speed
-				// 验证成功，更新代理信息
-				if err := c.storage.Save(ctx, proxy); err != nil {
-					logger.Log.Error("Failed to update proxy",
-						zap.String("ip", proxy.IP),
-						zap.String("port", proxy.Port),
-						zap.Error(err))
-				}
-				logger.Log.Info("Proxy check passed",
-					zap.String("ip", proxy.IP),
-					zap.String("port", proxy.Port),
-					zap.Int64("speed", speed))
-			} else {
-				// 验证失败，从存储中删除
-				key := proxy.IP + ":" + proxy.Port
-				if err := c.storage.Remove(ctx, key); err != nil {
-					logger.Log.Error("Failed to remove invalid proxy",
-						zap.String("ip", proxy.IP),
-						zap.String("port", proxy.Port),
-						zap.Error(err))
-				}
-				logger.Log.Info("Removed invalid proxy",
-					zap.String("ip", proxy.IP),
-					zap.String("port", proxy.Port))
-			}
+			break feed
+		case jobs <- proxy:
 		}
 	}
+	close(jobs)
+	wg.Wait()
+	close(done)
+
+	logger.Log.Info("Finished checking proxies",
+		zap.Int64("checked", stats.checked.Load()),
+		zap.Int("total", len(proxies)),
+		zap.Int64("passed", stats.passed.Load()),
+		zap.Int64("failed", stats.failed.Load()),
+		zap.Int64("removed", stats.removed.Load()),
+		zap.Duration("elapsed", time.Since(start)))
+	metrics.CheckDuration.Observe(time.Since(start).Seconds())
+	c.lastRun.Store(&RunStats{
+		StartedAt:   start,
+		FinishedAt:  time.Now(),
+		Total:       len(proxies),
+		Checked:     stats.checked.Load(),
+		Passed:      stats.passed.Load(),
+		Failed:      stats.failed.Load(),
+		Removed:     stats.removed.Load(),
+		Interrupted: ctx.Err() != nil,
+	})
 
-	logger.Log.Info("Finished checking all proxies")
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("proxy check interrupted: %w", err)
+	}
 	return nil
 }
+
+// LastRun 返回最近一次完成的检查统计，尚未完成过检查时返回 nil
+func (c *Checker) LastRun() *RunStats {
+	return c.lastRun.Load()
+}
+
+// checkProxy 验证单个代理并根据结果更新评分，分数降到淘汰线时删除
+func (c *Checker) checkProxy(ctx context.Context, proxy *model.Proxy, stats *checkStats) {
+	defer stats.checked.Add(1)
+
+	logger.Log.Debug("Checking proxy",
+		zap.String("ip", proxy.IP),
+		zap.String("port", proxy.Port))
+
+	// 验证代理
+	key := proxy.IP + ":" + proxy.Port
+	result := c.validator.Check(proxy)
+	proxy.AddCheck(result.Record(), config.GlobalConfig.GetHistorySize())
+	if result.Valid {
+		// 验证成功，加分并更新代理信息
+		stats.passed.Add(1)
+		metrics.CheckResults.WithLabelValues("passed").Inc()
+		result.Apply(proxy)
+		c.scorer.Success(proxy)
+		if err := c.storage.Save(ctx, proxy); err != nil {
+			logger.Log.Error("Failed to update proxy",
+				zap.String("ip", proxy.IP),
+				zap.String("port", proxy.Port),
+				zap.Error(err))
+		}
+		logger.Log.Debug("Proxy check passed",
+			zap.String("ip", proxy.IP),
+			zap.String("port", proxy.Port),
+			zap.Int64("speed", result.Speed),
+			zap.Int("score", proxy.Score))
+	} else if c.scorer.Failure(proxy) {
+		// 分数降到淘汰线，从存储中删除
+		stats.removed.Add(1)
+		metrics.CheckResults.WithLabelValues("removed").Inc()
+		if err := c.storage.Remove(ctx, key); err != nil {
+			logger.Log.Error("Failed to remove invalid proxy",
+				zap.String("ip", proxy.IP),
+				zap.String("port", proxy.Port),
+				zap.Error(err))
+		}
+		logger.Log.Debug("Removed invalid proxy",
+			zap.String("ip", proxy.IP),
+			zap.String("port", proxy.Port),
+			zap.Int("score", proxy.Score))
+	} else {
+		// 验证失败但分数未到淘汰线，只扣分并记录验证结果
+		stats.failed.Add(1)
+		metrics.CheckResults.WithLabelValues("failed").Inc()
+		if err := c.storage.UpdateStatus(ctx, proxy); err != nil {
+			logger.Log.Error("Failed to update proxy score",
+				zap.String("ip", proxy.IP),
+				zap.String("port", proxy.Port),
+				zap.Error(err))
+		}
+		logger.Log.Debug("Proxy check failed",
+			zap.String("ip", proxy.IP),
+			zap.String("port", proxy.Port),
+			zap.String("error", result.Error),
+			zap.Int("score", proxy.Score))
+	}
+}
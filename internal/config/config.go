@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -9,11 +10,17 @@ import (
 
 type Config struct {
 	Server    ServerConfig    `mapstructure:"server"`
+	Storage   StorageConfig   `mapstructure:"storage"`
 	Redis     RedisConfig     `mapstructure:"redis"`
 	Validator ValidatorConfig `mapstructure:"validator"`
 	Crawler   CrawlerConfig   `mapstructure:"crawler"`
 	Log       LogConfig       `mapstructure:"log"`
 	Security  SecurityConfig  `mapstructure:"security"`
+	Gateway   GatewayConfig   `mapstructure:"gateway"`
+	Score     ScoreConfig     `mapstructure:"score"`
+	Judge     JudgeConfig     `mapstructure:"judge"`
+	GeoIP     GeoIPConfig     `mapstructure:"geoip"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
 }
 
 type ServerConfig struct {
@@ -21,6 +28,12 @@ type ServerConfig struct {
 	Mode string `mapstructure:"mode"`
 }
 
+// StorageConfig 存储配置
+type StorageConfig struct {
+	Backend string `mapstructure:"backend"` // 存储后端：redis/memory/bolt
+	Path    string `mapstructure:"path"`    // bolt 数据文件路径
+}
+
 type RedisConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
@@ -32,11 +45,42 @@ type ValidatorConfig struct {
 	Timeout       int    `mapstructure:"timeout"`
 	CheckInterval int    `mapstructure:"check_interval"`
 	TestURL       string `mapstructure:"test_url"`
+	JudgeURL      string `mapstructure:"judge_url"`     // 检测匿名级别的判定接口，返回 httpbin 格式的 origin 和 headers
+	ConnectURL    string `mapstructure:"connect_url"`   // 探测 HTTP 代理是否支持 CONNECT 隧道的 https 地址
+	HistorySize   int    `mapstructure:"history_size"`  // 每个代理保留的验证记录条数
+	Concurrency   int    `mapstructure:"concurrency"`   // 定时检查时并发验证的协程数
+	CheckTimeout  int    `mapstructure:"check_timeout"` // 单次定时检查的最长耗时（分钟）
+
+	Targets []TargetConfig `mapstructure:"targets"` // 验证目标，未配置时使用 test_url
+}
+
+// TargetConfig 验证目标配置
+type TargetConfig struct {
+	Name           string `mapstructure:"name"`            // 目标名称
+	URL            string `mapstructure:"url"`             // 请求地址，https 地址要求代理支持隧道
+	ExpectedStatus int    `mapstructure:"expected_status"` // 期望的状态码，默认 200
+	Contains       string `mapstructure:"contains"`        // 响应内容需要包含的字符串
+	JSONField      string `mapstructure:"json_field"`      // 响应 JSON 中需要存在且非空的字段
 }
 
 type CrawlerConfig struct {
-	Interval  int `mapstructure:"interval"`
-	BatchSize int `mapstructure:"batch_size"`
+	Interval   int `mapstructure:"interval"`
+	BatchSize  int `mapstructure:"batch_size"`
+	Workers    int `mapstructure:"workers"`     // 并发验证的协程数
+	FetchDelay int `mapstructure:"fetch_delay"` // 代理源两次页面请求之间的间隔（秒）
+	MaxRetry   int `mapstructure:"max_retry"`   // 代理源请求失败时的最大尝试次数
+
+	Sources []SourceConfig `mapstructure:"sources"` // 通过配置声明的代理列表源
+}
+
+// SourceConfig 从本地文件或 URL 读取的代理列表源，url 和 path 二选一
+type SourceConfig struct {
+	Name      string `mapstructure:"name"`      // 代理源名称
+	URL       string `mapstructure:"url"`       // 代理列表地址
+	Path      string `mapstructure:"path"`      // 本地代理列表文件路径
+	Format    string `mapstructure:"format"`    // 列表格式：text/csv/json，为空时自动识别
+	Type      string `mapstructure:"type"`      // 列表中未给出类型时的默认类型，为空时自动探测协议
+	Anonymity string `mapstructure:"anonymity"` // 列表中未给出匿名级别时的初始匿名级别，检测成功后以检测结果为准
 }
 
 type LogConfig struct {
@@ -56,6 +100,9 @@ type SecurityConfig struct {
 	APIKeyEnabled bool     `mapstructure:"api_key_enabled"`
 	APIKeys       []string `mapstructure:"api_keys"`
 
+	// 管理员密钥，用于 /admin 管理接口和查看代理明文账号密码（请求头 X-Admin-Key），为空时不启用
+	AdminKey string `mapstructure:"admin_key"`
+
 	// 限流配置
 	RateLimit        int  `mapstructure:"rate_limit"`         // 每个时间窗口最大请求数
 	RateWindow       int  `mapstructure:"rate_window"`        // 时间窗口（分钟）
@@ -63,6 +110,45 @@ type SecurityConfig struct {
 	RateLimitEnabled bool `mapstructure:"rate_limit_enabled"` // 是否启用限流
 }
 
+// GatewayConfig 转发代理网关配置
+type GatewayConfig struct {
+	HTTPEnabled bool `mapstructure:"http_enabled"` // 是否启用 HTTP/HTTPS 转发代理
+	HTTPPort    int  `mapstructure:"http_port"`    // 转发代理监听端口
+	MaxRetry    int  `mapstructure:"max_retry"`    // 上游代理失败时的最大尝试次数
+	Timeout     int  `mapstructure:"timeout"`      // 连接上游代理的超时时间（秒），默认 10 秒
+
+	SOCKS5Enabled bool `mapstructure:"socks5_enabled"` // 是否启用 SOCKS5 代理服务
+	SOCKS5Port    int  `mapstructure:"socks5_port"`    // SOCKS5 监听端口
+
+	SessionTTL int `mapstructure:"session_ttl"` // 会话绑定代理的有效期（分钟）
+}
+
+// ScoreConfig 代理评分配置
+type ScoreConfig struct {
+	Initial   int `mapstructure:"initial"`   // 新代理的初始分数
+	Max       int `mapstructure:"max"`       // 分数上限
+	Min       int `mapstructure:"min"`       // 淘汰分数线，分数降到该值及以下时删除代理
+	Increment int `mapstructure:"increment"` // 每次验证成功增加的分数
+	Decrement int `mapstructure:"decrement"` // 每次验证失败扣除的分数
+}
+
+// JudgeConfig 内置代理判定接口配置
+type JudgeConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`    // 是否在 API 服务上提供 /judge 接口
+	PublicURL string `mapstructure:"public_url"` // 代理能够访问到的本服务地址，例如 http://1.2.3.4:8080
+}
+
+// GeoIPConfig 离线 GeoIP 数据库配置（MaxMind mmdb 格式），文件被替换后自动重新加载
+type GeoIPConfig struct {
+	CityDB string `mapstructure:"city_db"` // GeoLite2-City 数据库路径，为空时不使用
+	ASNDB  string `mapstructure:"asn_db"`  // GeoLite2-ASN 数据库路径，为空时不使用
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool `mapstructure:"enabled"` // 是否在 API 服务上提供 /metrics 接口
+}
+
 var (
 	GlobalConfig Config
 )
@@ -96,6 +182,46 @@ func (c *Config) GetCrawlerInterval() time.Duration {
 	return time.Duration(c.Crawler.Interval) * time.Minute
 }
 
+func (c *Config) GetFetchDelay() time.Duration {
+	return time.Duration(c.Crawler.FetchDelay) * time.Second
+}
+
 func (c *Config) GetCheckInterval() time.Duration {
 	return time.Duration(c.Validator.CheckInterval) * time.Minute
 }
+
+// GetHistorySize 每个代理保留的验证记录条数，未配置时默认 20 条
+func (c *Config) GetHistorySize() int {
+	if c.Validator.HistorySize <= 0 {
+		return 20
+	}
+	return c.Validator.HistorySize
+}
+
+// GetCheckTimeout 单次定时检查的最长耗时，未配置时使用检查间隔，避免与下一次检查重叠
+func (c *Config) GetCheckTimeout() time.Duration {
+	if c.Validator.CheckTimeout <= 0 {
+		return c.GetCheckInterval()
+	}
+	return time.Duration(c.Validator.CheckTimeout) * time.Minute
+}
+
+// GetJudgeURL 验证器使用的判定接口，启用内置判定接口并配置了公网地址时使用内置接口
+func (c *Config) GetJudgeURL() string {
+	if c.Judge.Enabled && c.Judge.PublicURL != "" {
+		return strings.TrimRight(c.Judge.PublicURL, "/") + "/judge"
+	}
+	return c.Validator.JudgeURL
+}
+
+// GetGatewayTimeout 网关连接上游代理的超时时间，未配置时默认 10 秒
+func (c *Config) GetGatewayTimeout() time.Duration {
+	if c.Gateway.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.Gateway.Timeout) * time.Second
+}
+
+func (c *Config) GetSessionTTL() time.Duration {
+	return time.Duration(c.Gateway.SessionTTL) * time.Minute
+}
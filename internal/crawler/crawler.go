@@ -2,34 +2,222 @@ package crawler
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/langchou/proxyPool/internal/config"
 	"github.com/langchou/proxyPool/internal/crawler/sources"
+	"github.com/langchou/proxyPool/internal/geoip"
 	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/metrics"
+	"github.com/langchou/proxyPool/internal/model"
+	"github.com/langchou/proxyPool/internal/score"
 	"github.com/langchou/proxyPool/internal/storage"
 	"github.com/langchou/proxyPool/internal/validator"
 	"go.uber.org/zap"
 )
 
+// Options 爬取流程的运行参数
+type Options struct {
+	BatchSize  int           // 每批验证的代理数量
+	Workers    int           // 并发验证的协程数
+	FetchDelay time.Duration // 代理源两次页面请求之间的间隔，0 表示使用代理源的默认值
+	MaxRetry   int           // 代理源请求失败时的最大尝试次数，0 表示使用代理源的默认值
+
+	Sources []sources.Source // 内置代理源之外的代理源，例如配置文件中声明的代理列表
+	GeoIP   *geoip.Reader    // 离线 GeoIP 数据库，用于补全出口地理位置，为 nil 时不使用
+}
+
+// candidate 抓取到的代理及其来源
+type candidate struct {
+	proxy  *model.Proxy
+	source string
+}
+
 type Manager struct {
 	sources   []sources.Source
 	storage   storage.Storage
 	validator *validator.Validator
+	scorer    *score.Scorer
+	options   Options
+	lastRun   atomic.Pointer[RunStats] // 最近一次完成的爬取统计
+}
+
+// crawlStats 单次爬取各阶段的统计
+type crawlStats struct {
+	fetched    atomic.Int64 // 代理源返回的代理数
+	duplicates atomic.Int64 // 去重阶段丢弃的重复代理数
+	detected   atomic.Int64 // 类型未知、经协议探测识别出协议的代理数
+	validated  atomic.Int64 // 已验证的代理数
+	valid      atomic.Int64 // 验证通过并保存的代理数
+	removed    atomic.Int64 // 验证失败且分数降到淘汰线被删除的已有代理数
+	batches    atomic.Int64 // 已完成的验证批次数
+
+	sources map[string]*sourceStats // 各代理源的统计，爬取开始前创建，之后只读
+}
+
+// sourceStats 单个代理源在一次爬取中的统计
+type sourceStats struct {
+	fetched   atomic.Int64
+	validated atomic.Int64
+	valid     atomic.Int64
+	added     atomic.Int64 // 首次加入代理池的代理数
+	err       string       // 抓取失败原因，只在抓取阶段写入
+}
+
+// RunStats 一次爬取的结果统计
+type RunStats struct {
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Fetched    int64         `json:"fetched"`    // 代理源返回的代理数
+	Duplicates int64         `json:"duplicates"` // 重复的代理数
+	Validated  int64         `json:"validated"`  // 已验证的代理数
+	Valid      int64         `json:"valid"`      // 验证通过并保存的代理数
+	Removed    int64         `json:"removed"`    // 验证失败被删除的已有代理数
+	Sources    []SourceYield `json:"sources"`    // 各代理源的产出，按名称排序
+}
+
+// SourceYield 单个代理源在一次爬取中的产出，去重后重复的代理计入最先抓取到它的代理源
+type SourceYield struct {
+	Name                 string  `json:"name"`
+	storage.SourceCounts         // 本次爬取的计数
+	Yield                float64 `json:"yield"`           // 验证通过数占抓取数的百分比
+	Error                string  `json:"error,omitempty"` // 抓取失败原因
+}
+
+func newCrawlStats(srcs []sources.Source) *crawlStats {
+	stats := &crawlStats{sources: make(map[string]*sourceStats, len(srcs))}
+	for _, s := range srcs {
+		stats.sources[s.Name()] = &sourceStats{}
+	}
+	return stats
 }
 
-func NewManager(storage storage.Storage, validator *validator.Validator) *Manager {
+// summary 汇总本次爬取的统计
+func (s *crawlStats) summary(start, end time.Time) *RunStats {
+	run := &RunStats{
+		StartedAt:  start,
+		FinishedAt: end,
+		Fetched:    s.fetched.Load(),
+		Duplicates: s.duplicates.Load(),
+		Validated:  s.validated.Load(),
+		Valid:      s.valid.Load(),
+		Removed:    s.removed.Load(),
+		Sources:    make([]SourceYield, 0, len(s.sources)),
+	}
+	for name, src := range s.sources {
+		yield := SourceYield{
+			Name: name,
+			SourceCounts: storage.SourceCounts{
+				Fetched:   src.fetched.Load(),
+				Validated: src.validated.Load(),
+				Valid:     src.valid.Load(),
+				Added:     src.added.Load(),
+			},
+			Error: src.err,
+		}
+		if yield.Fetched > 0 {
+			yield.Yield = float64(yield.Valid) * 100 / float64(yield.Fetched)
+		}
+		run.Sources = append(run.Sources, yield)
+	}
+	sort.Slice(run.Sources, func(i, j int) bool { return run.Sources[i].Name < run.Sources[j].Name })
+	return run
+}
+
+func NewManager(storage storage.Storage, validator *validator.Validator, scorer *score.Scorer, options Options) *Manager {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 20
+	}
+	if options.Workers <= 0 {
+		options.Workers = options.BatchSize
+	}
+
+	srcs := []sources.Source{
+		sources.NewKuaidailiSource(),
+		sources.NewOpenProxyListSource(),
+		// 添加更多代理源
+	}
+	srcs = append(srcs, options.Sources...)
+	for _, s := range srcs {
+		if options.FetchDelay > 0 {
+			s.SetFetchDelay(options.FetchDelay)
+		}
+		if options.MaxRetry > 0 {
+			s.SetMaxRetry(options.MaxRetry)
+		}
+	}
+
 	return &Manager{
-		sources: []sources.Source{
-			sources.NewKuaidailiSource(),
-			sources.NewOpenProxyListSource(),
-			// 添加更多代理源
-		},
+		sources:   srcs,
 		storage:   storage,
 		validator: validator,
+		scorer:    scorer,
+		options:   options,
 	}
 }
 
+// Run 执行一次爬取：抓取 -> 去重 -> 批量验证，各阶段通过 channel 连接
 func (m *Manager) Run(ctx context.Context) error {
+	start := time.Now()
+	stats := newCrawlStats(m.sources)
+	var errs []error
+	var fetchElapsed time.Duration
+
+	fetched := make(chan candidate, m.options.BatchSize)
+	unique := make(chan candidate, m.options.BatchSize)
+
+	go func() {
+		errs = m.fetch(ctx, fetched, stats)
+		fetchElapsed = time.Since(start)
+		close(fetched)
+	}()
+	go m.dedupe(ctx, fetched, unique, stats)
+	m.validate(ctx, unique, stats)
+
+	logger.Log.Info("Finished crawling proxies",
+		zap.Int64("fetched", stats.fetched.Load()),
+		zap.Int64("duplicates", stats.duplicates.Load()),
+		zap.Int64("detected", stats.detected.Load()),
+		zap.Int64("validated", stats.validated.Load()),
+		zap.Int64("valid", stats.valid.Load()),
+		zap.Int64("removed", stats.removed.Load()),
+		zap.Int64("batches", stats.batches.Load()),
+		zap.Duration("fetch_elapsed", fetchElapsed),
+		zap.Duration("elapsed", time.Since(start)))
+	metrics.CrawlDuration.Observe(time.Since(start).Seconds())
+
+	summary := stats.summary(start, time.Now())
+	m.lastRun.Store(summary)
+	m.recordSources(context.WithoutCancel(ctx), summary)
+
+	// 如果有错误，返回第一个错误
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return ctx.Err()
+}
+
+// LastRun 返回最近一次完成的爬取统计，尚未完成过爬取时返回 nil
+func (m *Manager) LastRun() *RunStats {
+	return m.lastRun.Load()
+}
+
+// recordSources 保存各代理源本次爬取的计数，用于统计代理源的长期质量
+func (m *Manager) recordSources(ctx context.Context, run *RunStats) {
+	for _, s := range run.Sources {
+		if err := m.storage.AddSourceCounts(ctx, s.Name, s.SourceCounts); err != nil {
+			logger.Log.Error("Failed to record source counts",
+				zap.String("source", s.Name),
+				zap.Error(err))
+		}
+	}
+}
+
+// fetch 并发从所有代理源抓取代理，写入 out
+func (m *Manager) fetch(ctx context.Context, out chan<- candidate, stats *crawlStats) []error {
 	var wg sync.WaitGroup
 	var errs []error
 	var mu sync.Mutex
@@ -39,58 +227,227 @@ func (m *Manager) Run(ctx context.Context) error {
 		go func(s sources.Source) {
 			defer wg.Done()
 
+			src := stats.sources[s.Name()]
+			start := time.Now()
 			proxies, err := s.Fetch()
+			metrics.CrawlFetchDuration.WithLabelValues(s.Name()).Observe(time.Since(start).Seconds())
 			if err != nil {
+				metrics.CrawlErrors.WithLabelValues(s.Name()).Inc()
+				logger.Log.Error("Failed to fetch proxies",
+					zap.String("source", s.Name()),
+					zap.Error(err))
 				mu.Lock()
 				errs = append(errs, err)
+				src.err = err.Error()
 				mu.Unlock()
 				return
 			}
+			logger.Log.Info("Fetched proxies from source",
+				zap.String("source", s.Name()),
+				zap.Int("count", len(proxies)),
+				zap.Duration("elapsed", time.Since(start)))
 
-			// 验证和存储代理
 			for _, proxy := range proxies {
 				select {
 				case <-ctx.Done():
 					return
-				default:
-					// 先验证再存储
-					valid, speed := m.validator.Validate(proxy)
-					if valid {
-						proxy.Speed = speed
-						proxy.Score = 100 // 初始分数
-						if err := m.storage.Save(ctx, proxy); err != nil {
-							mu.Lock()
-							errs = append(errs, err)
-							mu.Unlock()
-						}
-						logger.Log.Debug("Saved valid proxy",
-							zap.String("ip", proxy.IP),
-							zap.String("port", proxy.Port),
-							zap.String("type", string(proxy.Type)))
-					} else {
-						// 确保验证失败的代理被删除（以防之前存在）
-						key := proxy.IP + ":" + proxy.Port
-						if err := m.storage.Remove(ctx, key); err != nil {
-							logger.Log.Error("Failed to remove invalid proxy",
-								zap.String("ip", proxy.IP),
-								zap.String("port", proxy.Port),
-								zap.Error(err))
-						}
-						logger.Log.Debug("Removed invalid proxy",
-							zap.String("ip", proxy.IP),
-							zap.String("port", proxy.Port),
-							zap.String("type", string(proxy.Type)))
-					}
+				case out <- candidate{proxy: proxy, source: s.Name()}:
+					stats.fetched.Add(1)
+					src.fetched.Add(1)
+					metrics.CrawlFetched.WithLabelValues(s.Name()).Inc()
 				}
 			}
 		}(source)
 	}
 
 	wg.Wait()
+	return errs
+}
 
-	// 如果有错误，返回第一个错误
-	if len(errs) > 0 {
-		return errs[0]
+// dedupe 丢弃同一次爬取中重复的代理（不同代理源或同一源的不同页面）
+func (m *Manager) dedupe(ctx context.Context, in <-chan candidate, out chan<- candidate, stats *crawlStats) {
+	defer close(out)
+
+	seen := make(map[string]bool)
+	for c := range in {
+		key := c.proxy.IP + ":" + c.proxy.Port
+		if seen[key] {
+			stats.duplicates.Add(1)
+			continue
+		}
+		seen[key] = true
+
+		select {
+		case <-ctx.Done():
+			// 继续读取上游直到关闭，避免抓取协程阻塞
+			continue
+		case out <- c:
+		}
+	}
+}
+
+// validate 将代理按 batch_size 分批，每批在有限的协程池中并发验证
+func (m *Manager) validate(ctx context.Context, in <-chan candidate, stats *crawlStats) {
+	batch := make([]candidate, 0, m.options.BatchSize)
+	for c := range in {
+		batch = append(batch, c)
+		if len(batch) == m.options.BatchSize {
+			m.validateBatch(ctx, batch, stats)
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		m.validateBatch(ctx, batch, stats)
+	}
+}
+
+func (m *Manager) validateBatch(ctx context.Context, batch []candidate, stats *crawlStats) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	start := time.Now()
+	var valid atomic.Int64
+	jobs := make(chan candidate)
+	var wg sync.WaitGroup
+	for i := 0; i < min(m.options.Workers, len(batch)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				src := stats.sources[c.source]
+				src.validated.Add(1)
+				if m.process(ctx, c, stats) {
+					valid.Add(1)
+					src.valid.Add(1)
+					metrics.CrawlValid.WithLabelValues(c.source).Inc()
+				} else {
+					metrics.CrawlInvalid.WithLabelValues(c.source).Inc()
+				}
+			}
+		}()
+	}
+	for _, c := range batch {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats.batches.Add(1)
+	logger.Log.Debug("Validated proxy batch",
+		zap.Int("size", len(batch)),
+		zap.Int64("valid", valid.Load()),
+		zap.Int64("validated", stats.validated.Load()),
+		zap.Duration("elapsed", time.Since(start)))
+}
+
+// detect 为类型未知的代理确定协议，已存储的代理直接沿用之前的探测结果，返回是否识别出协议
+func (m *Manager) detect(existing, proxy *model.Proxy, stats *crawlStats) bool {
+	if existing != nil && existing.Type.IsValid() {
+		proxy.Type = existing.Type
+		proxy.Protocols = existing.Protocols
+		return true
+	}
+
+	protocols := m.validator.Detect(proxy)
+	if len(protocols) == 0 {
+		logger.Log.Debug("No protocol detected for proxy",
+			zap.String("ip", proxy.IP),
+			zap.String("port", proxy.Port))
+		return false
+	}
+
+	proxy.Type = validator.PrimaryType(protocols)
+	proxy.Protocols = protocols
+	stats.detected.Add(1)
+	return true
+}
+
+// process 验证单个代理并更新存储，返回代理是否可用
+func (m *Manager) process(ctx context.Context, c candidate, stats *crawlStats) bool {
+	defer stats.validated.Add(1)
+
+	proxy := c.proxy
+	key := proxy.IP + ":" + proxy.Port
+	existing, err := m.storage.Get(ctx, key)
+	if err != nil {
+		existing = nil
+	}
+	// 代理源不提供账号，已存储的代理沿用原有账号密码
+	if existing != nil && proxy.Username == "" {
+		proxy.Username = existing.Username
+		proxy.Password = existing.Password
+	}
+	if !proxy.Type.IsValid() && !m.detect(existing, proxy, stats) {
+		return false
+	}
+
+	// 先验证再存储
+	result := m.validator.Check(proxy)
+	if result.Valid {
+		// 检测失败时沿用已存储代理的匿名级别和出口 IP，
+		// 新代理只保留列表源配置的初始匿名级别，检测成功后以检测结果为准
+		proxy.ExitIP = ""
+		if existing != nil {
+			proxy.ExitIP = existing.ExitIP
+			if existing.Anonymity.IsValid() {
+				proxy.Anonymity = existing.Anonymity
+			}
+		}
+		result.Apply(proxy)
+		if m.options.GeoIP != nil {
+			m.options.GeoIP.Enrich(proxy)
+		}
+		// 已存在的代理保留原有分数和验证历史并加分，新代理使用初始分数
+		if existing != nil {
+			proxy.Score = existing.Score
+			proxy.History = existing.History
+			m.scorer.Success(proxy)
+		} else {
+			proxy.Score = m.scorer.Initial()
+		}
+		proxy.SetOrigin(existing, c.source)
+		proxy.AddCheck(result.Record(), config.GlobalConfig.GetHistorySize())
+		if err := m.storage.Save(ctx, proxy); err != nil {
+			logger.Log.Error("Failed to save proxy",
+				zap.String("ip", proxy.IP),
+				zap.String("port", proxy.Port),
+				zap.Error(err))
+			return false
+		}
+		stats.valid.Add(1)
+		if existing == nil {
+			stats.sources[c.source].added.Add(1)
+		}
+		logger.Log.Debug("Saved valid proxy",
+			zap.String("ip", proxy.IP),
+			zap.String("port", proxy.Port),
+			zap.String("type", string(proxy.Type)))
+		return true
+	}
+
+	if existing != nil {
+		// 已存在的代理验证失败时扣分，分数降到淘汰线才删除
+		existing.AddCheck(result.Record(), config.GlobalConfig.GetHistorySize())
+		if m.scorer.Failure(existing) {
+			if err := m.storage.Remove(ctx, key); err != nil {
+				logger.Log.Error("Failed to remove invalid proxy",
+					zap.String("ip", proxy.IP),
+					zap.String("port", proxy.Port),
+					zap.Error(err))
+				return false
+			}
+			stats.removed.Add(1)
+			logger.Log.Debug("Removed invalid proxy",
+				zap.String("ip", proxy.IP),
+				zap.String("port", proxy.Port),
+				zap.String("type", string(proxy.Type)))
+		} else if err := m.storage.UpdateStatus(ctx, existing); err != nil {
+			logger.Log.Error("Failed to update proxy score",
+				zap.String("ip", proxy.IP),
+				zap.String("port", proxy.Port),
+				zap.Error(err))
+		}
 	}
-	return nil
+	return false
 }
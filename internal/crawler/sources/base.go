@@ -1,16 +1,92 @@
 package sources
 
-import "github.com/langchou/proxyPool/internal/model"
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+	"go.uber.org/zap"
+)
 
 type Source interface {
 	Name() string
 	Fetch() ([]*model.Proxy, error)
+	SetFetchDelay(time.Duration)
+	SetMaxRetry(int)
 }
 
 type BaseSource struct {
-	name string
+	name       string
+	fetchDelay time.Duration // 两次页面请求之间的间隔
+	maxRetry   int           // 请求失败时的最大尝试次数
 }
 
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
 func (s *BaseSource) Name() string {
 	return s.name
 }
+
+// SetFetchDelay 设置两次页面请求之间的间隔
+func (s *BaseSource) SetFetchDelay(delay time.Duration) {
+	s.fetchDelay = delay
+}
+
+// SetMaxRetry 设置请求失败时的最大尝试次数
+func (s *BaseSource) SetMaxRetry(maxRetry int) {
+	s.maxRetry = maxRetry
+}
+
+// wait 在两次页面请求之间等待，避免请求过快被封
+func (s *BaseSource) wait() {
+	if s.fetchDelay > 0 {
+		time.Sleep(s.fetchDelay)
+	}
+}
+
+// get 请求页面，网络错误或服务端错误时最多尝试 maxRetry 次
+func (s *BaseSource) get(url string, header http.Header) (*http.Response, error) {
+	attempts := s.maxRetry
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			logger.Log.Debug("Retrying request",
+				zap.String("source", s.name),
+				zap.String("url", url),
+				zap.Int("attempt", i+1),
+				zap.Error(lastErr))
+			s.wait()
+		}
+
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
@@ -18,7 +18,7 @@ type KuaidailiSource struct {
 
 func NewKuaidailiSource() *KuaidailiSource {
 	return &KuaidailiSource{
-		BaseSource: BaseSource{name: "kuaidaili"},
+		BaseSource: BaseSource{name: "kuaidaili", fetchDelay: time.Second, maxRetry: 1},
 	}
 }
 
@@ -47,7 +47,7 @@ func (s *KuaidailiSource) Fetch() ([]*model.Proxy, error) {
 				zap.String("url", url),
 				zap.Int("count", len(newProxies)))
 			proxies = append(proxies, newProxies...)
-			time.Sleep(1 * time.Second)
+			s.wait()
 		}
 	}
 
@@ -60,15 +60,10 @@ func (s *KuaidailiSource) Fetch() ([]*model.Proxy, error) {
 func (s *KuaidailiSource) fetchPage(url, pageType string) ([]*model.Proxy, error) {
 	proxies := make([]*model.Proxy, 0)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
+	header := http.Header{}
+	header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := s.get(url, header)
 	if err != nil {
 		return nil, err
 	}
@@ -83,19 +78,15 @@ func (s *KuaidailiSource) fetchPage(url, pageType string) ([]*model.Proxy, error
 		ip := strings.TrimSpace(selection.Find("td[data-title='IP']").Text())
 		port := strings.TrimSpace(selection.Find("td[data-title='PORT']").Text())
 		typeStr := strings.ToLower(strings.TrimSpace(selection.Find("td[data-title='类型']").Text()))
-		anonymous := strings.Contains(strings.ToLower(selection.Find("td[data-title='匿名度']").Text()), "高匿")
 
 		if ip != "" && port != "" {
-			proxyType := s.parseProxyType(typeStr)
-			if proxyType.IsValid() {
-				proxies = append(proxies, &model.Proxy{
-					IP:        ip,
-					Port:      port,
-					Type:      proxyType,
-					Anonymous: anonymous,
-					LastCheck: time.Now(),
-				})
-			}
+			// socks 页面的类型列可能无法区分 SOCKS4/SOCKS5，类型留空，由爬虫在验证前探测协议
+			proxies = append(proxies, &model.Proxy{
+				IP:        ip,
+				Port:      port,
+				Type:      s.parseProxyType(typeStr),
+				LastCheck: time.Now(),
+			})
 		}
 	})
 
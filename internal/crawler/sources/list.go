@@ -0,0 +1,106 @@
+package sources
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+	"github.com/langchou/proxyPool/internal/parser"
+	"go.uber.org/zap"
+)
+
+// maxListSize 代理列表的大小上限
+const maxListSize = 20 << 20
+
+// ListConfig 通用代理列表源的配置，URL 和 Path 二选一
+type ListConfig struct {
+	Name      string // 代理源名称，为空时使用 URL 或文件路径
+	URL       string // 代理列表地址
+	Path      string // 本地代理列表文件路径
+	Format    string // 列表格式：text/csv/json，为空时根据内容自动识别
+	Type      string // 列表中未给出类型时使用的默认类型，为空时自动探测协议
+	Anonymity string // 列表中未给出匿名级别时使用的初始匿名级别，验证时检测成功后以检测结果为准
+}
+
+// ListSource 从本地文件或任意 URL 读取代理列表的通用代理源，每次抓取读取一次完整列表
+type ListSource struct {
+	BaseSource
+	url      string
+	path     string
+	format   string
+	defaults parser.Defaults
+}
+
+func NewListSource(cfg ListConfig) (*ListSource, error) {
+	if (cfg.URL == "") == (cfg.Path == "") {
+		return nil, fmt.Errorf("exactly one of url and path must be set")
+	}
+	switch cfg.Format {
+	case parser.FormatAuto, parser.FormatText, parser.FormatCSV, parser.FormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", cfg.Format)
+	}
+	proxyType, err := parser.ParseType(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
+	anonymity := model.ParseAnonymityLevel(cfg.Anonymity)
+	if cfg.Anonymity != "" && !anonymity.IsValid() {
+		return nil, fmt.Errorf("unsupported anonymity: %s", cfg.Anonymity)
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = cfg.URL + cfg.Path
+	}
+
+	return &ListSource{
+		BaseSource: BaseSource{name: name, maxRetry: 1},
+		url:        cfg.URL,
+		path:       cfg.Path,
+		format:     cfg.Format,
+		defaults:   parser.Defaults{Type: proxyType, Anonymity: anonymity},
+	}, nil
+}
+
+func (s *ListSource) Fetch() ([]*model.Proxy, error) {
+	logger.Log.Info("Starting to fetch proxies from list",
+		zap.String("source", s.Name()))
+
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	proxies, skipped, err := parser.Parse(data, s.format, s.defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("Finished fetching proxies",
+		zap.String("source", s.Name()),
+		zap.Int("total", len(proxies)),
+		zap.Int("skipped", skipped))
+	return proxies, nil
+}
+
+// read 读取本地文件或下载代理列表
+func (s *ListSource) read() ([]byte, error) {
+	if s.path != "" {
+		f, err := os.Open(s.path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxListSize))
+	}
+
+	resp, err := s.get(s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, maxListSize))
+}
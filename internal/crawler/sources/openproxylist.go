@@ -3,7 +3,6 @@ package sources
 import (
 	"bufio"
 	"fmt"
-	"net/http"
 	"github.com/langchou/proxyPool/internal/logger"
 	"github.com/langchou/proxyPool/internal/model"
 	"strings"
@@ -18,7 +17,7 @@ type OpenProxyListSource struct {
 
 func NewOpenProxyListSource() *OpenProxyListSource {
 	return &OpenProxyListSource{
-		BaseSource: BaseSource{name: "openproxylist"},
+		BaseSource: BaseSource{name: "openproxylist", fetchDelay: 2 * time.Second, maxRetry: 1},
 	}
 }
 
@@ -43,7 +42,7 @@ func (s *OpenProxyListSource) Fetch() ([]*model.Proxy, error) {
 		}
 		proxies = append(proxies, newProxies...)
 		// 避免请求过快
-		time.Sleep(2 * time.Second)
+		s.wait()
 	}
 
 	logger.Log.Info("Finished fetching proxies",
@@ -55,13 +54,7 @@ func (s *OpenProxyListSource) Fetch() ([]*model.Proxy, error) {
 func (s *OpenProxyListSource) fetchList(url, proxyType string) ([]*model.Proxy, error) {
 	proxies := make([]*model.Proxy, 0)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := s.get(url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -99,12 +92,12 @@ func (s *OpenProxyListSource) fetchList(url, proxyType string) ([]*model.Proxy,
 			speed = 0
 		}
 
+		// 列表中没有匿名度信息，匿名级别由验证器检测
 		proxy := &model.Proxy{
 			IP:        ipPort[0],
 			Port:      ipPort[1],
 			Type:      s.getProxyType(proxyType),
 			Speed:     speed,
-			Anonymous: true, // 这些代理通常都是高匿的
 			LastCheck: time.Now(),
 		}
 		proxies = append(proxies, proxy)
@@ -0,0 +1,112 @@
+package dialer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+
+	"golang.org/x/net/proxy"
+)
+
+// Dial 通过代理建立到目标地址的 TCP 连接
+// HTTP/HTTPS 代理使用 CONNECT 隧道，SOCKS 代理使用 SOCKS 握手
+func Dial(ctx context.Context, p *model.Proxy, addr string, timeout time.Duration) (net.Conn, error) {
+	switch p.Type {
+	case model.ProxyTypeHTTP, model.ProxyTypeHTTPS:
+		return dialConnect(ctx, p, addr, timeout)
+	case model.ProxyTypeSOCKS4:
+		return dialSocks4(ctx, p, addr, timeout)
+	case model.ProxyTypeSOCKS5:
+		return dialSocks5(ctx, p, addr, timeout)
+	default:
+		return nil, fmt.Errorf("unsupported proxy type: %s", p.Type)
+	}
+}
+
+// dialConnect 通过 HTTP CONNECT 建立隧道
+func dialConnect(ctx context.Context, p *model.Proxy, addr string, timeout time.Duration) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(p.IP, p.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	// 握手阶段设置超时，完成后清除
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	// 代理带有账号时使用基本认证
+	if p.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT returned status %d", resp.StatusCode)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	// 代理可能在响应后立即发送数据，保留已缓冲的内容
+	if br.Buffered() > 0 {
+		return NewBufferedConn(conn, br), nil
+	}
+	return conn, nil
+}
+
+// dialSocks5 通过 SOCKS5 代理建立连接，代理带有账号时使用用户名密码认证
+func dialSocks5(ctx context.Context, p *model.Proxy, addr string, timeout time.Duration) (net.Conn, error) {
+	var auth *proxy.Auth
+	if p.Username != "" {
+		auth = &proxy.Auth{User: p.Username, Password: p.Password}
+	}
+
+	d, err := proxy.SOCKS5("tcp", net.JoinHostPort(p.IP, p.Port), auth, &net.Dialer{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+}
+
+// BufferedConn 包装了带缓冲读取的连接，读取时先返回已缓冲的数据
+type BufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func NewBufferedConn(conn net.Conn, r *bufio.Reader) *BufferedConn {
+	return &BufferedConn{Conn: conn, r: r}
+}
+
+func (c *BufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
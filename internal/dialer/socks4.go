@@ -0,0 +1,78 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// SOCKS4 协议常量
+const (
+	socks4Version = 0x04
+	socks4Connect = 0x01
+	socks4Granted = 0x5a
+)
+
+// dialSocks4 通过 SOCKS4 代理建立连接，目标为域名时使用 SOCKS4a 由代理解析
+func dialSocks4(ctx context.Context, p *model.Proxy, addr string, timeout time.Duration) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port: %s", portStr)
+	}
+
+	req := []byte{socks4Version, socks4Connect, byte(port >> 8), byte(port)}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		// SOCKS4a：目标 IP 填 0.0.0.x（x 非 0），域名附在用户 ID 之后
+		req = append(req, 0, 0, 0, 1)
+		req = append(req, p.Username...)
+		req = append(req, 0)
+		req = append(req, host...)
+		req = append(req, 0)
+	case ip.To4() != nil:
+		req = append(req, ip.To4()...)
+		req = append(req, p.Username...)
+		req = append(req, 0)
+	default:
+		return nil, errors.New("socks4 does not support IPv6 destinations")
+	}
+
+	d := &net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(p.IP, p.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	// 握手阶段设置超时，完成后清除
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// 响应固定 8 字节：VN、CD、DSTPORT、DSTIP
+	resp := make([]byte, 8)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp[1] != socks4Granted {
+		conn.Close()
+		return nil, fmt.Errorf("socks4 request rejected with code %d", resp[1])
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
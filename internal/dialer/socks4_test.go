@@ -0,0 +1,122 @@
+package dialer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// socks4Server 启动只处理一个连接的 SOCKS4 代理，读取请求后返回指定的响应码，请求内容通过 channel 返回
+func socks4Server(t *testing.T, reply byte) (*model.Proxy, <-chan []byte) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	requests := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// 请求由 8 字节头部和以 0 结尾的用户 ID 组成，SOCKS4a 之后还有以 0 结尾的域名
+		req := make([]byte, 8)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		nulls := 1
+		if bytes.Equal(req[4:7], []byte{0, 0, 0}) && req[7] != 0 {
+			nulls = 2
+		}
+		b := make([]byte, 1)
+		for nulls > 0 {
+			if _, err := conn.Read(b); err != nil {
+				return
+			}
+			req = append(req, b[0])
+			if b[0] == 0 {
+				nulls--
+			}
+		}
+		requests <- req
+
+		conn.Write([]byte{0, reply, 0, 0, 0, 0, 0, 0})
+		if reply == socks4Granted {
+			conn.Write([]byte("hello"))
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return &model.Proxy{IP: "127.0.0.1", Port: strconv.Itoa(addr.Port), Type: model.ProxyTypeSOCKS4, Username: "user"}, requests
+}
+
+func TestDialSocks4(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		want []byte
+	}{
+		{
+			name: "socks4",
+			addr: "10.1.2.3:443",
+			want: []byte{socks4Version, socks4Connect, 0x01, 0xbb, 10, 1, 2, 3, 'u', 's', 'e', 'r', 0},
+		},
+		{
+			name: "socks4a",
+			addr: "example.com:80",
+			want: append([]byte{socks4Version, socks4Connect, 0x00, 0x50, 0, 0, 0, 1, 'u', 's', 'e', 'r', 0},
+				append([]byte("example.com"), 0)...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, requests := socks4Server(t, socks4Granted)
+
+			conn, err := dialSocks4(context.Background(), p, tt.addr, time.Second)
+			if err != nil {
+				t.Fatalf("dialSocks4() error = %v", err)
+			}
+			defer conn.Close()
+
+			if req := <-requests; !bytes.Equal(req, tt.want) {
+				t.Errorf("request = %v, want %v", req, tt.want)
+			}
+
+			// 握手完成后连接可以继续读取代理转发的数据
+			buf := make([]byte, 5)
+			if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+				t.Errorf("read after handshake = %q, %v", buf, err)
+			}
+		})
+	}
+}
+
+func TestDialSocks4Rejected(t *testing.T) {
+	p, requests := socks4Server(t, 0x5b)
+
+	conn, err := dialSocks4(context.Background(), p, "10.1.2.3:80", time.Second)
+	if err == nil {
+		conn.Close()
+		t.Fatal("dialSocks4() error = nil, want rejection")
+	}
+	<-requests
+}
+
+func TestDialSocks4IPv6(t *testing.T) {
+	p := &model.Proxy{IP: "127.0.0.1", Port: "1", Type: model.ProxyTypeSOCKS4}
+	if _, err := dialSocks4(context.Background(), p, "[2001:db8::1]:80", time.Second); err == nil {
+		t.Error("dialSocks4() error = nil, want error for IPv6 destination")
+	}
+}
@@ -0,0 +1,231 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/config"
+	"github.com/langchou/proxyPool/internal/dialer"
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// 逐跳头部，转发时需要移除
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// HTTPProxy 基于代理池的 HTTP/HTTPS 转发代理
+type HTTPProxy struct {
+	*upstream
+}
+
+func NewHTTPProxy(storage storage.Storage, timeout time.Duration, maxRetry int, sessionTTL time.Duration) *HTTPProxy {
+	return &HTTPProxy{upstream: newUpstream(storage, timeout, maxRetry, sessionTTL)}
+}
+
+func (g *HTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := g.authorize(r)
+	if !ok {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="proxypool"`)
+		http.Error(w, "Proxy Authentication Required", http.StatusProxyAuthRequired)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		g.handleConnect(w, r, sessionID)
+		return
+	}
+	g.handleHTTP(w, r, sessionID)
+}
+
+// authorize 启用基本认证时校验 Proxy-Authorization，并解析会话 ID
+// 会话 ID 优先取自 X-Proxy-Session 请求头，其次取自用户名后缀
+func (g *HTTPProxy) authorize(r *http.Request) (string, bool) {
+	sessionID := r.Header.Get(SessionHeader)
+	r.Header.Del(SessionHeader)
+
+	username, password, hasAuth := parseProxyAuth(r.Header.Get("Proxy-Authorization"))
+	username, userSession := splitSessionUser(username)
+	if sessionID == "" {
+		sessionID = userSession
+	}
+
+	if !config.GlobalConfig.Security.AuthEnabled {
+		return sessionID, true
+	}
+
+	return sessionID, hasAuth && username == config.GlobalConfig.Security.Username &&
+		password == config.GlobalConfig.Security.Password
+}
+
+// handleConnect 处理 HTTPS 隧道请求
+func (g *HTTPProxy) handleConnect(w http.ResponseWriter, r *http.Request, sessionID string) {
+	remote, p, err := g.dial(r.Context(), r.Host, sessionID)
+	if err != nil {
+		logger.Log.Warn("Gateway CONNECT failed",
+			zap.String("host", r.Host),
+			zap.Error(err))
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		remote.Close()
+		http.Error(w, "Hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		remote.Close()
+		logger.Log.Error("Failed to hijack connection", zap.Error(err))
+		return
+	}
+	// 客户端可能在 CONNECT 请求后立即发送数据，保留已缓冲的内容
+	client := conn
+	if brw.Reader.Buffered() > 0 {
+		client = dialer.NewBufferedConn(conn, brw.Reader)
+	}
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		client.Close()
+		remote.Close()
+		return
+	}
+
+	logger.Log.Debug("Gateway tunnel established",
+		zap.String("host", r.Host),
+		zap.String("upstream", p.IP+":"+p.Port))
+
+	pipe(client, remote)
+}
+
+// handleHTTP 处理普通 HTTP 请求，失败时换用其他上游代理重试
+func (g *HTTPProxy) handleHTTP(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if !r.URL.IsAbs() {
+		http.Error(w, "This is a proxy server, absolute URL required", http.StatusBadRequest)
+		return
+	}
+
+	// 缓存请求体以便重试
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tried := make(map[string]bool)
+	var lastErr error = errNoProxy
+
+	for attempt := 0; attempt < g.maxRetry; attempt++ {
+		p, err := g.pick(r.Context(), sessionID, tried)
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		outreq := r.Clone(r.Context())
+		outreq.RequestURI = ""
+		outreq.Body = io.NopCloser(bytes.NewReader(body))
+		removeHopHeaders(outreq.Header)
+
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.Dial(ctx, p, addr, g.timeout)
+			},
+			DisableKeepAlives:     true,
+			ResponseHeaderTimeout: g.timeout,
+		}
+
+		resp, err := transport.RoundTrip(outreq)
+		if err != nil {
+			logger.Log.Debug("Gateway upstream request failed",
+				zap.String("url", r.URL.String()),
+				zap.String("upstream", p.IP+":"+p.Port),
+				zap.Int("attempt", attempt+1),
+				zap.Error(err))
+			lastErr = err
+			continue
+		}
+
+		g.bind(sessionID, p)
+
+		removeHopHeaders(resp.Header)
+		for k, vv := range resp.Header {
+			for _, v := range vv {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		resp.Body.Close()
+		return
+	}
+
+	logger.Log.Warn("Gateway request failed",
+		zap.String("url", r.URL.String()),
+		zap.Error(lastErr))
+	http.Error(w, "Bad Gateway", http.StatusBadGateway)
+}
+
+// pipe 在两个连接之间双向转发数据
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	a.Close()
+	b.Close()
+	<-done
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
+
+// parseProxyAuth 解析 Proxy-Authorization 基本认证头
+func parseProxyAuth(auth string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+
+	username, password, ok = strings.Cut(string(decoded), ":")
+	return username, password, ok
+}
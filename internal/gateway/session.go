@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionHeader 客户端指定会话 ID 的请求头
+const SessionHeader = "X-Proxy-Session"
+
+// sessionSep 用户名中会话 ID 的分隔符，例如 user-session-abc
+const sessionSep = "-session-"
+
+type session struct {
+	proxyKey string
+	expires  time.Time
+}
+
+// sessionManager 维护会话 ID 到上游代理的绑定关系
+type sessionManager struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	sessions  map[string]*session
+	lastPurge time.Time
+}
+
+func newSessionManager(ttl time.Duration) *sessionManager {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &sessionManager{
+		ttl:       ttl,
+		sessions:  make(map[string]*session),
+		lastPurge: time.Now(),
+	}
+}
+
+// get 获取会话绑定的代理，过期的会话视为不存在
+func (m *sessionManager) get(id string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(s.expires) {
+		delete(m.sessions, id)
+		return "", false
+	}
+	return s.proxyKey, true
+}
+
+// bind 绑定会话到代理，每次使用都会刷新过期时间
+func (m *sessionManager) bind(id, proxyKey string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sessions[id] = &session{
+		proxyKey: proxyKey,
+		expires:  now.Add(m.ttl),
+	}
+
+	// 定期清理过期会话
+	if now.Sub(m.lastPurge) > m.ttl {
+		for k, s := range m.sessions {
+			if now.After(s.expires) {
+				delete(m.sessions, k)
+			}
+		}
+		m.lastPurge = now
+	}
+}
+
+func (m *sessionManager) delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+}
+
+// splitSessionUser 从用户名中拆分出会话 ID，例如 user-session-abc
+func splitSessionUser(username string) (user, sessionID string) {
+	if i := strings.LastIndex(username, sessionSep); i >= 0 {
+		return username[:i], username[i+len(sessionSep):]
+	}
+	return username, ""
+}
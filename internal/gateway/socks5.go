@@ -0,0 +1,277 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/config"
+	"github.com/langchou/proxyPool/internal/dialer"
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// SOCKS5 协议常量
+const (
+	socks5Version = 0x05
+
+	authNone         = 0x00
+	authUserPass     = 0x02
+	authNoAcceptable = 0xff
+
+	userPassVersion = 0x01
+
+	cmdConnect = 0x01
+
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	repSuccess             = 0x00
+	repHostUnreachable     = 0x04
+	repCommandNotSupported = 0x07
+	repAddrNotSupported    = 0x08
+)
+
+// SOCKS5Server 基于代理池的 SOCKS5 代理服务
+type SOCKS5Server struct {
+	*upstream
+}
+
+func NewSOCKS5Server(storage storage.Storage, timeout time.Duration, maxRetry int, sessionTTL time.Duration) *SOCKS5Server {
+	return &SOCKS5Server{upstream: newUpstream(storage, timeout, maxRetry, sessionTTL)}
+}
+
+// ListenAndServe 监听指定地址并处理 SOCKS5 连接
+func (s *SOCKS5Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve 在给定的监听器上处理 SOCKS5 连接
+func (s *SOCKS5Server) Serve(ln net.Listener) error {
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *SOCKS5Server) handleConn(conn net.Conn) {
+	// 握手阶段设置超时，防止客户端长时间占用连接
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	br := bufio.NewReader(conn)
+
+	sessionID, err := s.negotiate(br, conn)
+	if err != nil {
+		logger.Log.Debug("SOCKS5 negotiation failed",
+			zap.String("client", conn.RemoteAddr().String()),
+			zap.Error(err))
+		conn.Close()
+		return
+	}
+
+	addr, err := readRequest(br, conn)
+	if err != nil {
+		logger.Log.Debug("SOCKS5 request failed",
+			zap.String("client", conn.RemoteAddr().String()),
+			zap.Error(err))
+		conn.Close()
+		return
+	}
+
+	// 握手完成后清除超时，连接上游时可能依次尝试多个代理，总耗时由 dial 的 context 限制
+	conn.SetDeadline(time.Time{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout*time.Duration(s.maxRetry))
+	remote, p, err := s.dial(ctx, addr, sessionID)
+	cancel()
+	if err != nil {
+		logger.Log.Warn("SOCKS5 gateway dial failed",
+			zap.String("addr", addr),
+			zap.Error(err))
+		writeReply(conn, repHostUnreachable)
+		conn.Close()
+		return
+	}
+
+	if err := writeReply(conn, repSuccess); err != nil {
+		remote.Close()
+		conn.Close()
+		return
+	}
+
+	logger.Log.Debug("SOCKS5 tunnel established",
+		zap.String("addr", addr),
+		zap.String("upstream", p.IP+":"+p.Port))
+
+	pipe(dialer.NewBufferedConn(conn, br), remote)
+}
+
+// negotiate 协商认证方式，启用基本认证时要求用户名密码，返回用户名中携带的会话 ID
+// 未启用认证时，如果客户端提供了用户名密码，也会接受以便读取会话 ID
+func (s *SOCKS5Server) negotiate(r *bufio.Reader, w io.Writer) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", err
+	}
+	if header[0] != socks5Version {
+		return "", fmt.Errorf("unsupported SOCKS version: %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return "", err
+	}
+
+	method := byte(authNoAcceptable)
+	for _, m := range methods {
+		if m == authUserPass {
+			method = authUserPass
+			break
+		}
+		if m == authNone && !config.GlobalConfig.Security.AuthEnabled {
+			method = authNone
+		}
+	}
+	if method == authNoAcceptable {
+		w.Write([]byte{socks5Version, authNoAcceptable})
+		return "", errors.New("no acceptable authentication method")
+	}
+
+	if _, err := w.Write([]byte{socks5Version, method}); err != nil {
+		return "", err
+	}
+
+	if method == authUserPass {
+		return authenticate(r, w)
+	}
+	return "", nil
+}
+
+// authenticate 处理用户名密码子协商（RFC 1929），返回会话 ID
+func authenticate(r *bufio.Reader, w io.Writer) (string, error) {
+	username, password, err := readUserPass(r)
+	if err != nil {
+		return "", err
+	}
+
+	username, sessionID := splitSessionUser(username)
+	if config.GlobalConfig.Security.AuthEnabled &&
+		(username != config.GlobalConfig.Security.Username ||
+			password != config.GlobalConfig.Security.Password) {
+		w.Write([]byte{userPassVersion, 0x01})
+		return "", fmt.Errorf("invalid credentials for user %q", username)
+	}
+
+	_, err = w.Write([]byte{userPassVersion, 0x00})
+	return sessionID, err
+}
+
+func readUserPass(r *bufio.Reader) (string, string, error) {
+	ver, err := r.ReadByte()
+	if err != nil {
+		return "", "", err
+	}
+	if ver != userPassVersion {
+		return "", "", fmt.Errorf("unsupported auth version: %d", ver)
+	}
+
+	username, err := readString(r)
+	if err != nil {
+		return "", "", err
+	}
+	password, err := readString(r)
+	if err != nil {
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+// readString 读取一个长度前缀的字符串
+func readString(r *bufio.Reader) (string, error) {
+	n, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// readRequest 读取客户端请求，返回目标地址
+func readRequest(r *bufio.Reader, w io.Writer) (string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", err
+	}
+	if header[0] != socks5Version {
+		return "", fmt.Errorf("unsupported SOCKS version: %d", header[0])
+	}
+	if header[1] != cmdConnect {
+		writeReply(w, repCommandNotSupported)
+		return "", fmt.Errorf("unsupported command: %d", header[1])
+	}
+
+	var host string
+	switch header[3] {
+	case atypIPv4:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case atypIPv6:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case atypDomain:
+		domain, err := readString(r)
+		if err != nil {
+			return "", err
+		}
+		host = domain
+	default:
+		writeReply(w, repAddrNotSupported)
+		return "", fmt.Errorf("unsupported address type: %d", header[3])
+	}
+
+	portBuf := make([]byte, 2)
+	if _, err := io.ReadFull(r, portBuf); err != nil {
+		return "", err
+	}
+	port := binary.BigEndian.Uint16(portBuf)
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// writeReply 写入响应，绑定地址统一返回 0.0.0.0:0
+func writeReply(w io.Writer, rep byte) error {
+	_, err := w.Write([]byte{socks5Version, rep, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
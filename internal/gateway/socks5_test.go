@@ -0,0 +1,268 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/config"
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+	"github.com/langchou/proxyPool/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+func TestSOCKS5Negotiate(t *testing.T) {
+	userPass := func(username, password string) []byte {
+		b := []byte{userPassVersion, byte(len(username))}
+		b = append(b, username...)
+		b = append(b, byte(len(password)))
+		return append(b, password...)
+	}
+	concat := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	tests := []struct {
+		name        string
+		authEnabled bool
+		input       []byte
+		wantOutput  []byte
+		wantSession string
+		wantErr     bool
+	}{
+		{
+			name:       "no auth",
+			input:      []byte{socks5Version, 1, authNone},
+			wantOutput: []byte{socks5Version, authNone},
+		},
+		{
+			name:        "session id without auth",
+			input:       concat([]byte{socks5Version, 2, authNone, authUserPass}, userPass("any-session-abc", "x")),
+			wantOutput:  []byte{socks5Version, authUserPass, userPassVersion, 0x00},
+			wantSession: "abc",
+		},
+		{
+			name:        "auth required",
+			authEnabled: true,
+			input:       []byte{socks5Version, 1, authNone},
+			wantOutput:  []byte{socks5Version, authNoAcceptable},
+			wantErr:     true,
+		},
+		{
+			name:        "valid credentials",
+			authEnabled: true,
+			input:       concat([]byte{socks5Version, 1, authUserPass}, userPass("admin-session-s1", "secret")),
+			wantOutput:  []byte{socks5Version, authUserPass, userPassVersion, 0x00},
+			wantSession: "s1",
+		},
+		{
+			name:        "invalid credentials",
+			authEnabled: true,
+			input:       concat([]byte{socks5Version, 1, authUserPass}, userPass("admin", "wrong")),
+			wantOutput:  []byte{socks5Version, authUserPass, userPassVersion, 0x01},
+			wantErr:     true,
+		},
+		{
+			name:    "unsupported version",
+			input:   []byte{0x04, 1, authNone},
+			wantErr: true,
+		},
+		{
+			name:    "truncated methods",
+			input:   []byte{socks5Version, 2, authNone},
+			wantErr: true,
+		},
+	}
+
+	security := config.GlobalConfig.Security
+	defer func() { config.GlobalConfig.Security = security }()
+
+	s := &SOCKS5Server{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.GlobalConfig.Security.AuthEnabled = tt.authEnabled
+			config.GlobalConfig.Security.Username = "admin"
+			config.GlobalConfig.Security.Password = "secret"
+
+			var out bytes.Buffer
+			session, err := s.negotiate(bufio.NewReader(bytes.NewReader(tt.input)), &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("negotiate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if session != tt.wantSession {
+				t.Errorf("negotiate() session = %q, want %q", session, tt.wantSession)
+			}
+			if !bytes.Equal(out.Bytes(), tt.wantOutput) {
+				t.Errorf("negotiate() wrote %v, want %v", out.Bytes(), tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestSOCKS5ReadRequest(t *testing.T) {
+	domain := append([]byte{socks5Version, cmdConnect, 0x00, atypDomain, 11}, "example.com"...)
+
+	tests := []struct {
+		name      string
+		input     []byte
+		want      string
+		wantReply byte // 出错时期望写入的响应码，0 表示不写入响应
+		wantErr   bool
+	}{
+		{
+			name:  "ipv4",
+			input: []byte{socks5Version, cmdConnect, 0x00, atypIPv4, 10, 1, 2, 3, 0x01, 0xbb},
+			want:  "10.1.2.3:443",
+		},
+		{
+			name:  "domain",
+			input: append(domain, 0x00, 0x50),
+			want:  "example.com:80",
+		},
+		{
+			name: "ipv6",
+			input: []byte{socks5Version, cmdConnect, 0x00, atypIPv6,
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0x1f, 0x90},
+			want: "[2001:db8::1]:8080",
+		},
+		{
+			name:      "bind not supported",
+			input:     []byte{socks5Version, 0x02, 0x00, atypIPv4, 10, 1, 2, 3, 0, 80},
+			wantReply: repCommandNotSupported,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown address type",
+			input:     []byte{socks5Version, cmdConnect, 0x00, 0x05, 10, 1, 2, 3, 0, 80},
+			wantReply: repAddrNotSupported,
+			wantErr:   true,
+		},
+		{
+			name:    "unsupported version",
+			input:   []byte{0x04, cmdConnect, 0x00, atypIPv4, 10, 1, 2, 3, 0, 80},
+			wantErr: true,
+		},
+		{
+			name:    "truncated port",
+			input:   []byte{socks5Version, cmdConnect, 0x00, atypIPv4, 10, 1, 2, 3, 0},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			addr, err := readRequest(bufio.NewReader(bytes.NewReader(tt.input)), &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if addr != tt.want {
+				t.Errorf("readRequest() = %q, want %q", addr, tt.want)
+			}
+
+			var wantOutput []byte
+			if tt.wantReply != 0 {
+				wantOutput = []byte{socks5Version, tt.wantReply, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0}
+			}
+			if !bytes.Equal(out.Bytes(), wantOutput) {
+				t.Errorf("readRequest() wrote %v, want %v", out.Bytes(), wantOutput)
+			}
+		})
+	}
+}
+
+// fixedRandomStorage 随机选择时总是返回指定代理
+type fixedRandomStorage struct {
+	storage.Storage
+	proxy *model.Proxy
+}
+
+func (s *fixedRandomStorage) GetRandom(ctx context.Context) (*model.Proxy, error) {
+	return s.proxy, nil
+}
+
+// TestSOCKS5RetryAfterUpstreamTimeout 第一个上游代理超时后换用其他代理，客户端连接不应因握手超时被断开
+func TestSOCKS5RetryAfterUpstreamTimeout(t *testing.T) {
+	logger.Log = zap.NewNop()
+
+	// 接受连接但从不响应的上游代理
+	blackhole, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer blackhole.Close()
+	go func() {
+		conn, err := blackhole.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	// 正常的 SOCKS4 上游代理
+	working, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer working.Close()
+	go func() {
+		conn, err := working.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// 请求为 8 字节头部和以 0 结尾的用户 ID
+		buf := make([]byte, 9)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		conn.Write([]byte{0, 0x5a, 0, 0, 0, 0, 0, 0})
+		conn.Write([]byte("hello"))
+	}()
+
+	proxyFor := func(ln net.Listener) *model.Proxy {
+		port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+		return &model.Proxy{IP: "127.0.0.1", Port: port, Type: model.ProxyTypeSOCKS4}
+	}
+	store := storage.NewMemoryStorage()
+	stuck := proxyFor(blackhole)
+	store.Save(context.Background(), stuck)
+
+	timeout := 300 * time.Millisecond
+	s := NewSOCKS5Server(&fixedRandomStorage{Storage: store, proxy: proxyFor(working)}, timeout, 2, time.Minute)
+	// 会话绑定到无响应的代理，使第一次尝试必然超时
+	s.sessions.bind("s1", stuck.IP+":"+stuck.Port)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go s.handleConn(server)
+
+	username := "user-session-s1"
+	greeting := []byte{socks5Version, 1, authUserPass, userPassVersion, byte(len(username))}
+	greeting = append(greeting, username...)
+	greeting = append(greeting, 1, 'x')
+	greeting = append(greeting, socks5Version, cmdConnect, 0x00, atypIPv4, 10, 1, 2, 3, 0, 80)
+	go client.Write(greeting)
+
+	client.SetDeadline(time.Now().Add(5 * timeout))
+	reply := make([]byte, 2+2+10)
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatalf("read reply failed: %v", err)
+	}
+	if reply[5] != repSuccess {
+		t.Fatalf("reply code = %d, want success", reply[5])
+	}
+
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(client, buf); err != nil || string(buf) != "hello" {
+		t.Errorf("read through tunnel = %q, %v", buf, err)
+	}
+}
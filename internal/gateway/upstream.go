@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/dialer"
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+	"github.com/langchou/proxyPool/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+var errNoProxy = errors.New("no available upstream proxy")
+
+// upstream 负责从代理池中选择上游代理并建立连接
+type upstream struct {
+	storage  storage.Storage
+	sessions *sessionManager
+	timeout  time.Duration
+	maxRetry int
+}
+
+func newUpstream(storage storage.Storage, timeout time.Duration, maxRetry int, sessionTTL time.Duration) *upstream {
+	if maxRetry < 1 {
+		maxRetry = 1
+	}
+	return &upstream{
+		storage:  storage,
+		sessions: newSessionManager(sessionTTL),
+		timeout:  timeout,
+		maxRetry: maxRetry,
+	}
+}
+
+// dial 从代理池选择上游代理连接目标地址，失败时换用其他代理
+// sessionID 不为空时优先使用会话绑定的代理，连接成功后更新绑定
+func (u *upstream) dial(ctx context.Context, addr, sessionID string) (net.Conn, *model.Proxy, error) {
+	tried := make(map[string]bool)
+	var lastErr error = errNoProxy
+
+	for attempt := 0; attempt < u.maxRetry; attempt++ {
+		p, err := u.pick(ctx, sessionID, tried)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		conn, err := dialer.Dial(ctx, p, addr, u.timeout)
+		if err != nil {
+			logger.Log.Debug("Gateway upstream dial failed",
+				zap.String("addr", addr),
+				zap.String("upstream", p.IP+":"+p.Port),
+				zap.Int("attempt", attempt+1),
+				zap.Error(err))
+			lastErr = err
+			continue
+		}
+		u.bind(sessionID, p)
+		return conn, p, nil
+	}
+
+	return nil, nil, lastErr
+}
+
+// pick 选择一个上游代理，会话已绑定代理时优先使用绑定的代理，
+// 否则随机选择并尽量避开已尝试过的代理和不支持隧道的代理
+func (u *upstream) pick(ctx context.Context, sessionID string, tried map[string]bool) (*model.Proxy, error) {
+	if sessionID != "" {
+		if key, ok := u.sessions.get(sessionID); ok && !tried[key] {
+			tried[key] = true
+			p, err := u.storage.Get(ctx, key)
+			if err == nil {
+				return p, nil
+			}
+
+			// 绑定的代理已被检查器移除，改用新的代理
+			logger.Log.Debug("Session proxy no longer available",
+				zap.String("session", sessionID),
+				zap.String("proxy", key))
+			u.sessions.delete(sessionID)
+		}
+	}
+
+	var p *model.Proxy
+	for i := 0; i < u.maxRetry; i++ {
+		candidate, err := u.storage.GetRandom(ctx)
+		if err != nil {
+			return nil, errNoProxy
+		}
+		p = candidate
+		if !tried[p.IP+":"+p.Port] && canTunnel(p) {
+			break
+		}
+	}
+	tried[p.IP+":"+p.Port] = true
+	return p, nil
+}
+
+// bind 将会话绑定到指定代理
+func (u *upstream) bind(sessionID string, p *model.Proxy) {
+	if sessionID == "" {
+		return
+	}
+	u.sessions.bind(sessionID, p.IP+":"+p.Port)
+}
+
+// canTunnel 代理是否能建立到任意地址的隧道，网关的所有连接都通过隧道转发
+func canTunnel(p *model.Proxy) bool {
+	switch p.Type {
+	case model.ProxyTypeSOCKS4, model.ProxyTypeSOCKS5:
+		return true
+	default:
+		return p.HasType(model.ProxyTypeHTTPS)
+	}
+}
@@ -0,0 +1,258 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+)
+
+// reloadDelay 数据库文件变化后等待写入完成再重新加载
+const reloadDelay = time.Second
+
+// cityRecord GeoLite2-City 数据库中用到的字段
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord GeoLite2-ASN 数据库中用到的字段
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Reader 离线 GeoIP 数据库（MaxMind mmdb 格式），数据库文件被替换后自动重新加载
+type Reader struct {
+	cityPath string
+	asnPath  string
+
+	mu   sync.RWMutex
+	city *maxminddb.Reader
+	asn  *maxminddb.Reader
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewReader 打开 City 和 ASN 数据库，路径为空的数据库不加载
+func NewReader(cityPath, asnPath string) (*Reader, error) {
+	r := &Reader{
+		cityPath: cityPath,
+		asnPath:  asnPath,
+		done:     make(chan struct{}),
+	}
+
+	var err error
+	if r.city, err = open(cityPath); err != nil {
+		return nil, err
+	}
+	if r.asn, err = open(asnPath); err != nil {
+		r.closeDBs()
+		return nil, err
+	}
+
+	if err := r.watch(); err != nil {
+		r.closeDBs()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Lookup 查询 IP 的地理位置和所属网络，查不到时返回 false
+func (r *Reader) Lookup(ip string) (model.GeoInfo, bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return model.GeoInfo{}, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var geo model.GeoInfo
+	if r.city != nil {
+		var record cityRecord
+		if err := r.city.Lookup(addr, &record); err == nil {
+			geo.Country = model.NormalizeCountry(record.Country.ISOCode)
+			if len(record.Subdivisions) > 0 {
+				geo.Region = record.Subdivisions[0].Names["en"]
+			}
+			geo.City = record.City.Names["en"]
+		}
+	}
+	if r.asn != nil {
+		var record asnRecord
+		if err := r.asn.Lookup(addr, &record); err == nil {
+			geo.ASN = record.Number
+			geo.Org = record.Organization
+		}
+	}
+	return geo, !geo.IsZero()
+}
+
+// Enrich 用数据库补全代理出口 IP 的地理位置，验证时已获得的字段保留原值。没有出口 IP 时使用代理 IP
+func (r *Reader) Enrich(p *model.Proxy) {
+	ip := p.ExitIP
+	if ip == "" {
+		ip = p.IP
+	}
+	geo, ok := r.Lookup(ip)
+	if !ok {
+		return
+	}
+
+	if p.Country == "" {
+		p.Country = geo.Country
+		p.Region = geo.Region
+		p.City = geo.City
+	}
+	if p.ASN == 0 {
+		p.ASN = geo.ASN
+		p.Org = geo.Org
+	}
+}
+
+// Close 停止监听文件并关闭数据库
+func (r *Reader) Close() error {
+	close(r.done)
+	err := r.watcher.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeDBs()
+	return err
+}
+
+// watch 监听数据库所在目录。数据库通常通过重命名新文件替换，直接监听文件会在替换后失效
+func (r *Reader) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create file watcher failed: %w", err)
+	}
+
+	dirs := make(map[string]bool)
+	for _, path := range []string{r.cityPath, r.asnPath} {
+		if path != "" {
+			dirs[filepath.Dir(path)] = true
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("watch %s failed: %w", dir, err)
+		}
+	}
+
+	r.watcher = watcher
+	go r.watchLoop()
+	return nil
+}
+
+func (r *Reader) watchLoop() {
+	// 每个数据库一个计时器，短时间内的多次变化只重新加载一次
+	timers := make(map[string]*time.Timer)
+	defer func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+
+			path := filepath.Clean(event.Name)
+			if path != filepath.Clean(r.cityPath) && path != filepath.Clean(r.asnPath) {
+				continue
+			}
+			if t, ok := timers[path]; ok {
+				t.Stop()
+			}
+			timers[path] = time.AfterFunc(reloadDelay, func() { r.reload(path) })
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Log.Error("GeoIP file watcher error", zap.Error(err))
+		}
+	}
+}
+
+// reload 重新打开发生变化的数据库，打开失败时继续使用旧数据库
+func (r *Reader) reload(path string) {
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+
+	db, err := open(path)
+	if err != nil {
+		logger.Log.Error("Failed to reload GeoIP database", zap.String("path", path), zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	var old *maxminddb.Reader
+	if path == filepath.Clean(r.cityPath) {
+		old, r.city = r.city, db
+	} else {
+		old, r.asn = r.asn, db
+	}
+	r.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	logger.Log.Info("Reloaded GeoIP database",
+		zap.String("path", path),
+		zap.String("type", db.Metadata.DatabaseType),
+		zap.Uint("build_epoch", db.Metadata.BuildEpoch))
+}
+
+// closeDBs 关闭已打开的数据库，调用方需持有写锁或确保没有并发访问
+func (r *Reader) closeDBs() {
+	if r.city != nil {
+		r.city.Close()
+		r.city = nil
+	}
+	if r.asn != nil {
+		r.asn.Close()
+		r.asn = nil
+	}
+}
+
+// open 打开 mmdb 数据库，路径为空时返回 nil
+func open(path string) (*maxminddb.Reader, error) {
+	if path == "" {
+		return nil, nil
+	}
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open GeoIP database %s failed: %w", path, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "proxypool"
+
+// 爬虫指标
+var (
+	CrawlFetched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawl",
+		Name:      "fetched_total",
+		Help:      "Number of proxies fetched from each source.",
+	}, []string{"source"})
+
+	CrawlValid = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawl",
+		Name:      "valid_total",
+		Help:      "Number of crawled proxies that passed validation, by source.",
+	}, []string{"source"})
+
+	CrawlInvalid = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawl",
+		Name:      "invalid_total",
+		Help:      "Number of crawled proxies that failed validation, by source.",
+	}, []string{"source"})
+
+	CrawlErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawl",
+		Name:      "errors_total",
+		Help:      "Number of failed fetches from each source.",
+	}, []string{"source"})
+
+	CrawlFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "crawl",
+		Name:      "fetch_duration_seconds",
+		Help:      "Time spent fetching proxies from each source.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300},
+	}, []string{"source"})
+
+	CrawlDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "crawl",
+		Name:      "duration_seconds",
+		Help:      "Duration of a full crawl run, including validation.",
+		Buckets:   []float64{10, 30, 60, 120, 300, 600, 1200, 1800},
+	})
+)
+
+// 检查器指标
+var (
+	CheckDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "check",
+		Name:      "duration_seconds",
+		Help:      "Duration of a full checker run.",
+		Buckets:   []float64{10, 30, 60, 120, 300, 600, 1200, 1800},
+	})
+
+	CheckResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "check",
+		Name:      "results_total",
+		Help:      "Number of checked proxies by result (passed, failed, removed).",
+	}, []string{"result"})
+)
+
+// 验证器指标
+var (
+	Validations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "validation",
+		Name:      "total",
+		Help:      "Number of proxy validations by proxy type and result (valid or failure class).",
+	}, []string{"type", "result"})
+
+	ValidationLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "validation",
+		Name:      "latency_seconds",
+		Help:      "Response time of successful validation requests by proxy type and target.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10},
+	}, []string{"type", "target"})
+)
+
+// API 指标
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of API requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "API request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	RateLimitBans = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "bans_total",
+		Help:      "Number of client IPs banned for exceeding the rate limit.",
+	})
+)
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// poolQueryTimeout 每次采集时查询存储的超时时间
+const poolQueryTimeout = 10 * time.Second
+
+var (
+	poolSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "proxies"),
+		"Number of proxies in the pool.",
+		nil, nil)
+	poolTypeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "proxies_by_type"),
+		"Number of proxies by supported type; a proxy supporting several types is counted once per type.",
+		[]string{"type"}, nil)
+	poolAnonymityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "proxies_by_anonymity"),
+		"Number of proxies by anonymity level.",
+		[]string{"anonymity"}, nil)
+	poolCountryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "proxies_by_country"),
+		"Number of proxies by exit country.",
+		[]string{"country"}, nil)
+)
+
+// PoolCollector 采集时通过存储的索引统计代理池的大小
+type PoolCollector struct {
+	storage storage.Storage
+}
+
+func NewPoolCollector(storage storage.Storage) *PoolCollector {
+	return &PoolCollector{storage: storage}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolSizeDesc
+	ch <- poolTypeDesc
+	ch <- poolAnonymityDesc
+	ch <- poolCountryDesc
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), poolQueryTimeout)
+	defer cancel()
+
+	// 通过存储的索引统计，不读取全部代理数据
+	stats, err := c.storage.Stats(ctx)
+	if err != nil {
+		logger.Log.Error("Failed to collect pool metrics", zap.Error(err))
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(poolSizeDesc, prometheus.GaugeValue, float64(stats.Total))
+	for t, n := range stats.ByType {
+		ch <- prometheus.MustNewConstMetric(poolTypeDesc, prometheus.GaugeValue, float64(n), t)
+	}
+	for level, n := range stats.ByAnonymity {
+		ch <- prometheus.MustNewConstMetric(poolAnonymityDesc, prometheus.GaugeValue, float64(n), level)
+	}
+	for country, n := range stats.ByCountry {
+		ch <- prometheus.MustNewConstMetric(poolCountryDesc, prometheus.GaugeValue, float64(n), country)
+	}
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"crypto/subtle"
+
 	"github.com/langchou/proxyPool/internal/api/response"
 	"github.com/langchou/proxyPool/internal/config"
 	"github.com/langchou/proxyPool/internal/logger"
@@ -62,3 +64,28 @@ func APIKeyAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminAuth 管理接口认证中间件，要求请求头 X-Admin-Key 与配置的管理员密钥一致
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(c) {
+			logger.Log.Warn("Invalid admin key attempt",
+				zap.String("ip", c.ClientIP()),
+				zap.String("path", c.Request.URL.Path))
+
+			response.Error(c, "Invalid admin key")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin 检查请求是否携带了有效的管理员密钥（X-Admin-Key 请求头）
+func IsAdmin(c *gin.Context) bool {
+	adminKey := config.GlobalConfig.Security.AdminKey
+	if adminKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Key")), []byte(adminKey)) == 1
+}
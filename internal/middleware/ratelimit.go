@@ -10,12 +10,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/langchou/proxyPool/internal/api/response"
 	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/metrics"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
+// rateStore 限流计数和封禁状态的存储
+type rateStore interface {
+	IsBanned(ctx context.Context, ip string) bool
+	Ban(ctx context.Context, ip string, duration time.Duration) error
+	Unban(ctx context.Context, ip string) error
+	Incr(ctx context.Context, ip string, window time.Duration) (int64, error)
+}
+
 type RateLimiter struct {
-	redisClient *redis.Client
+	store       rateStore
 	maxRequests int           // 最大请求次数
 	duration    time.Duration // 时间窗口
 	banDuration time.Duration // 封禁时长
@@ -23,7 +32,17 @@ type RateLimiter struct {
 
 func NewRateLimiter(redisClient *redis.Client, maxRequests int, duration, banDuration time.Duration) *RateLimiter {
 	return &RateLimiter{
-		redisClient: redisClient,
+		store:       &redisRateStore{client: redisClient},
+		maxRequests: maxRequests,
+		duration:    duration,
+		banDuration: banDuration,
+	}
+}
+
+// NewMemoryRateLimiter 创建使用进程内计数的限流器，用于未使用 Redis 的部署
+func NewMemoryRateLimiter(maxRequests int, duration, banDuration time.Duration) *RateLimiter {
+	return &RateLimiter{
+		store:       newMemoryRateStore(),
 		maxRequests: maxRequests,
 		duration:    duration,
 		banDuration: banDuration,
@@ -35,8 +54,7 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 		ip := c.ClientIP()
 
 		// 检查是否被封禁
-		banKey := fmt.Sprintf("ban:%s", ip)
-		if banned, _ := rl.redisClient.Get(c.Request.Context(), banKey).Bool(); banned {
+		if rl.store.IsBanned(c.Request.Context(), ip) {
 			logger.Log.Warn("Banned IP attempted access",
 				zap.String("ip", ip),
 				zap.String("path", c.Request.URL.Path))
@@ -49,23 +67,13 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 			return
 		}
 
-		// 访问计数key
-		key := fmt.Sprintf("ratelimit:%s", ip)
-
-		// 使用 Redis 的 MULTI 命令保证原子性
-		pipe := rl.redisClient.Pipeline()
-		incr := pipe.Incr(c.Request.Context(), key)
-		pipe.Expire(c.Request.Context(), key, rl.duration)
-		_, err := pipe.Exec(c.Request.Context())
-
+		count, err := rl.store.Incr(c.Request.Context(), ip, rl.duration)
 		if err != nil {
-			logger.Log.Error("Redis pipeline failed", zap.Error(err))
+			logger.Log.Error("Rate limit counter failed", zap.Error(err))
 			c.Next()
 			return
 		}
 
-		count := incr.Val()
-
 		// 检查是否超过限制
 		if count > int64(rl.maxRequests) {
 			// 封禁IP
@@ -93,12 +101,46 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 }
 
 func (rl *RateLimiter) banIP(ctx context.Context, ip string) {
-	banKey := fmt.Sprintf("ban:%s", ip)
-	rl.redisClient.Set(ctx, banKey, true, rl.banDuration)
+	if err := rl.store.Ban(ctx, ip, rl.banDuration); err != nil {
+		logger.Log.Error("Failed to ban IP", zap.String("ip", ip), zap.Error(err))
+		return
+	}
+	metrics.RateLimitBans.Inc()
 }
 
 // 解封IP的方法（可用于管理API）
 func (rl *RateLimiter) UnbanIP(ctx context.Context, ip string) error {
-	banKey := fmt.Sprintf("ban:%s", ip)
-	return rl.redisClient.Del(ctx, banKey).Err()
+	return rl.store.Unban(ctx, ip)
+}
+
+// redisRateStore 基于 Redis 的限流存储，多实例共享计数
+type redisRateStore struct {
+	client *redis.Client
+}
+
+func (s *redisRateStore) IsBanned(ctx context.Context, ip string) bool {
+	banned, _ := s.client.Get(ctx, fmt.Sprintf("ban:%s", ip)).Bool()
+	return banned
+}
+
+func (s *redisRateStore) Ban(ctx context.Context, ip string, duration time.Duration) error {
+	return s.client.Set(ctx, fmt.Sprintf("ban:%s", ip), true, duration).Err()
+}
+
+func (s *redisRateStore) Unban(ctx context.Context, ip string) error {
+	return s.client.Del(ctx, fmt.Sprintf("ban:%s", ip)).Err()
+}
+
+func (s *redisRateStore) Incr(ctx context.Context, ip string, window time.Duration) (int64, error) {
+	// 访问计数key
+	key := fmt.Sprintf("ratelimit:%s", ip)
+
+	// 使用 Redis 的 MULTI 命令保证原子性
+	pipe := s.client.Pipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
 }
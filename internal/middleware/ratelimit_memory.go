@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type rateCounter struct {
+	count   int64
+	expires time.Time
+}
+
+// memoryRateStore 进程内的限流存储，行为与 Redis 版本一致：每次请求刷新计数窗口
+type memoryRateStore struct {
+	mu       sync.Mutex
+	counters map[string]*rateCounter
+	bans     map[string]time.Time
+	lastGC   time.Time
+}
+
+func newMemoryRateStore() *memoryRateStore {
+	return &memoryRateStore{
+		counters: make(map[string]*rateCounter),
+		bans:     make(map[string]time.Time),
+		lastGC:   time.Now(),
+	}
+}
+
+func (s *memoryRateStore) IsBanned(ctx context.Context, ip string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.bans[ip]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(s.bans, ip)
+		return false
+	}
+	return true
+}
+
+func (s *memoryRateStore) Ban(ctx context.Context, ip string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bans[ip] = time.Now().Add(duration)
+	return nil
+}
+
+func (s *memoryRateStore) Unban(ctx context.Context, ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.bans, ip)
+	return nil
+}
+
+func (s *memoryRateStore) Incr(ctx context.Context, ip string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.gc(now, window)
+
+	counter, ok := s.counters[ip]
+	if !ok || now.After(counter.expires) {
+		counter = &rateCounter{}
+		s.counters[ip] = counter
+	}
+	counter.count++
+	counter.expires = now.Add(window)
+	return counter.count, nil
+}
+
+// gc 定期清理过期的计数和封禁记录，调用方需持有锁
+func (s *memoryRateStore) gc(now time.Time, window time.Duration) {
+	if now.Sub(s.lastGC) < window {
+		return
+	}
+
+	for ip, counter := range s.counters {
+		if now.After(counter.expires) {
+			delete(s.counters, ip)
+		}
+	}
+	for ip, until := range s.bans {
+		if now.After(until) {
+			delete(s.bans, ip)
+		}
+	}
+	s.lastGC = now
+}
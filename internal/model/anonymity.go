@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// AnonymityLevel 代理匿名级别
+type AnonymityLevel string
+
+const (
+	AnonymityUnknown     AnonymityLevel = ""            // 尚未检测
+	AnonymityTransparent AnonymityLevel = "transparent" // 透明代理，目标能看到真实 IP
+	AnonymityAnonymous   AnonymityLevel = "anonymous"   // 普通匿名，隐藏真实 IP 但暴露了代理特征
+	AnonymityElite       AnonymityLevel = "elite"       // 高匿代理，目标无法察觉使用了代理
+)
+
+// IsValid 检查匿名级别是否有效（未检测不算有效级别）
+func (l AnonymityLevel) IsValid() bool {
+	switch l {
+	case AnonymityTransparent, AnonymityAnonymous, AnonymityElite:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseAnonymityLevel 解析匿名级别，不区分大小写
+func ParseAnonymityLevel(s string) AnonymityLevel {
+	level := AnonymityLevel(strings.ToLower(strings.TrimSpace(s)))
+	if !level.IsValid() {
+		return AnonymityUnknown
+	}
+	return level
+}
+
+// UnmarshalJSON 兼容旧版本数据中的 anonymous 字段，旧数据中的高匿代理视为 elite
+func (p *Proxy) UnmarshalJSON(data []byte) error {
+	type proxyAlias Proxy
+	aux := struct {
+		*proxyAlias
+		Anonymous *bool `json:"anonymous"`
+	}{proxyAlias: (*proxyAlias)(p)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if p.Anonymity == AnonymityUnknown && aux.Anonymous != nil && *aux.Anonymous {
+		p.Anonymity = AnonymityElite
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GeoInfo 出口 IP 的地理位置和所属网络
+type GeoInfo struct {
+	Country string `json:"country,omitempty"` // 国家代码（ISO 3166-1 alpha-2，大写）
+	Region  string `json:"region,omitempty"`  // 省/州
+	City    string `json:"city,omitempty"`    // 城市
+	ASN     uint   `json:"asn,omitempty"`     // 自治系统号
+	Org     string `json:"org,omitempty"`     // 自治系统所属组织
+}
+
+// IsZero 是否没有任何地理位置信息
+func (g GeoInfo) IsZero() bool {
+	return g == GeoInfo{}
+}
+
+// ParseASN 解析自治系统号，兼容 "AS15169" 和 "15169" 两种写法，无法解析时返回 0
+func ParseASN(s string) uint {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(asn)
+}
+
+// NormalizeCountry 统一国家代码为大写
+func NormalizeCountry(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// CheckRecord 单次验证结果
+type CheckRecord struct {
+	Time    time.Time `json:"time"`            // 验证时间
+	Success bool      `json:"success"`         // 是否验证通过
+	Latency int64     `json:"latency"`         // 响应耗时（毫秒），失败时可能为 0
+	Error   string    `json:"error,omitempty"` // 失败原因分类，例如 timeout、refused
+}
+
+// HistoryStats 根据验证历史计算的可靠性统计
+type HistoryStats struct {
+	Checks              int     `json:"checks"`               // 历史中的验证次数
+	Uptime              float64 `json:"uptime"`               // 验证成功率（百分比）
+	MedianLatency       int64   `json:"median_latency_ms"`    // 成功验证的响应耗时中位数（毫秒）
+	P95Latency          int64   `json:"p95_latency_ms"`       // 成功验证的响应耗时 P95（毫秒）
+	ConsecutiveFailures int     `json:"consecutive_failures"` // 最近连续失败次数
+}
+
+// AddCheck 追加一条验证记录，超过 limit 条时丢弃最早的记录
+func (p *Proxy) AddCheck(record CheckRecord, limit int) {
+	p.History = append(p.History, record)
+	if limit > 0 && len(p.History) > limit {
+		// 复制到新切片，避免底层数组无限增长
+		p.History = append([]CheckRecord(nil), p.History[len(p.History)-limit:]...)
+	}
+}
+
+// Stats 计算验证历史的统计信息
+func (p *Proxy) Stats() HistoryStats {
+	stats := HistoryStats{Checks: len(p.History)}
+	if stats.Checks == 0 {
+		return stats
+	}
+
+	var latencies []int64
+	for _, record := range p.History {
+		if record.Success {
+			latencies = append(latencies, record.Latency)
+		}
+	}
+	stats.Uptime = float64(len(latencies)) * 100 / float64(stats.Checks)
+
+	for i := len(p.History) - 1; i >= 0 && !p.History[i].Success; i-- {
+		stats.ConsecutiveFailures++
+	}
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats.MedianLatency = Percentile(latencies, 50)
+		stats.P95Latency = Percentile(latencies, 95)
+	}
+	return stats
+}
+
+// Percentile 使用最近秩法计算已排序数据的百分位数
+func Percentile(sorted []int64, pct int) int64 {
+	rank := (pct*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
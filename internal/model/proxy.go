@@ -13,17 +13,99 @@ const (
 )
 
 type Proxy struct {
-	IP        string    `json:"ip"`
-	Port      string    `json:"port"`
-	Type      ProxyType `json:"type"`      // 代理类型
-	Anonymous bool      `json:"anonymous"` // 是否高匿
-	Speed     int64     `json:"speed"`     // 响应速度（毫秒）
-	Score     int       `json:"score"`     // 可用性评分
-	LastCheck time.Time `json:"last_check"`
+	IP        string         `json:"ip"`
+	Port      string         `json:"port"`
+	Type      ProxyType      `json:"type"`               // 代理类型
+	Username  string         `json:"username,omitempty"` // 代理认证用户名，SOCKS4 代理作为用户 ID
+	Password  string         `json:"password,omitempty"` // 代理认证密码
+	Anonymity AnonymityLevel `json:"anonymity"`          // 匿名级别
+	Speed     int64          `json:"speed"`              // 响应速度（毫秒）
+	Score     int            `json:"score"`              // 可用性评分
+	LastCheck time.Time      `json:"last_check"`
+	Source    string         `json:"source,omitempty"`  // 最先发现该代理的代理源名称
+	FirstSeen time.Time      `json:"first_seen"`        // 首次验证通过并加入代理池的时间
+	Targets   []string       `json:"targets,omitempty"` // 最近一次验证通过的验证目标
+	ExitIP    string         `json:"exit_ip,omitempty"` // 通过代理访问时目标看到的出口 IP，可能与代理 IP 不同
+	GeoInfo                  // 出口 IP 的地理位置和所属网络
+
+	SupportsHTTP    bool        `json:"supports_http"`       // HTTP 代理是否支持普通转发
+	SupportsConnect bool        `json:"supports_connect"`    // HTTP 代理是否支持 CONNECT 隧道（可访问 https 地址）
+	Protocols       []ProxyType `json:"protocols,omitempty"` // 类型未知的代理经协议探测得到的全部协议
+
+	History []CheckRecord `json:"history,omitempty"` // 最近的验证记录，按时间从旧到新
 }
 
 type ProxyList []*Proxy
 
+// SupportedTypes 代理实际支持的类型。HTTP 代理按探测结果区分普通转发（http）和 CONNECT 隧道（https），
+// 尚未探测过的代理使用来源声明的类型，协议探测发现的其他协议也包含在内
+func (p *Proxy) SupportedTypes() []ProxyType {
+	var types []ProxyType
+	add := func(t ProxyType) {
+		for _, existing := range types {
+			if existing == t {
+				return
+			}
+		}
+		types = append(types, t)
+	}
+
+	isHTTP := p.Type == ProxyTypeHTTP || p.Type == ProxyTypeHTTPS
+	if isHTTP && (p.SupportsHTTP || p.SupportsConnect) {
+		if p.SupportsHTTP {
+			add(ProxyTypeHTTP)
+		}
+		if p.SupportsConnect {
+			add(ProxyTypeHTTPS)
+		}
+	} else {
+		add(p.Type)
+	}
+
+	// HTTP 代理的 http/https 以验证时的探测结果为准，不使用协议探测的结果
+	for _, t := range p.Protocols {
+		if isHTTP && (t == ProxyTypeHTTP || t == ProxyTypeHTTPS) {
+			continue
+		}
+		add(t)
+	}
+	return types
+}
+
+// SetOrigin 记录代理的来源和加入代理池的时间，已存储的代理沿用最初的记录
+func (p *Proxy) SetOrigin(existing *Proxy, source string) {
+	if existing != nil && existing.Source != "" {
+		p.Source = existing.Source
+	} else if p.Source == "" {
+		p.Source = source
+	}
+	if existing != nil && !existing.FirstSeen.IsZero() {
+		p.FirstSeen = existing.FirstSeen
+	} else if p.FirstSeen.IsZero() {
+		p.FirstSeen = time.Now()
+	}
+}
+
+// HasType 代理是否支持指定类型
+func (p *Proxy) HasType(t ProxyType) bool {
+	for _, supported := range p.SupportedTypes() {
+		if supported == t {
+			return true
+		}
+	}
+	return false
+}
+
+// PassedTarget 代理最近一次验证是否通过了指定的验证目标
+func (p *Proxy) PassedTarget(name string) bool {
+	for _, target := range p.Targets {
+		if target == name {
+			return true
+		}
+	}
+	return false
+}
+
 // IsValid 检查代理类型是否有效
 func (t ProxyType) IsValid() bool {
 	switch t {
@@ -0,0 +1,349 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// 代理列表格式
+const (
+	FormatAuto = ""     // 根据内容自动识别
+	FormatText = "text" // 每行一个代理：ip:port、type://ip:port、type://user:pass@ip:port 或 ip:port:user:pass
+	FormatCSV  = "csv"  // CSV，带表头时按列名解析，否则依次为 ip、port、type、username、password
+	FormatJSON = "json" // JSON 数组，元素为代理对象或单行格式的字符串
+)
+
+// Defaults 代理列表中没有给出类型或匿名级别时使用的默认值
+type Defaults struct {
+	Type      model.ProxyType
+	Anonymity model.AnonymityLevel
+}
+
+// jsonProxy JSON 格式中的代理对象，端口可以是数字或字符串
+type jsonProxy struct {
+	Proxy     string      `json:"proxy"` // 单行格式的代理，设置后忽略其他地址字段
+	IP        string      `json:"ip"`
+	Port      json.Number `json:"port"`
+	Type      string      `json:"type"`
+	Username  string      `json:"username"`
+	Password  string      `json:"password"`
+	Anonymity string      `json:"anonymity"`
+}
+
+// Parse 按指定格式解析代理列表，返回解析成功的代理和无法解析而跳过的条目数
+func Parse(data []byte, format string, defaults Defaults) ([]*model.Proxy, int, error) {
+	if format == FormatAuto {
+		format = detectFormat(data)
+	}
+
+	var proxies []*model.Proxy
+	var skipped int
+	var err error
+	switch format {
+	case FormatText:
+		proxies, skipped = parseText(data)
+	case FormatCSV:
+		proxies, skipped, err = parseCSV(data)
+	case FormatJSON:
+		proxies, skipped, err = parseJSON(data)
+	default:
+		return nil, 0, fmt.Errorf("unsupported format: %s", format)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	for _, p := range proxies {
+		if p.Type == "" {
+			p.Type = defaults.Type
+		}
+		if p.Anonymity == model.AnonymityUnknown {
+			p.Anonymity = defaults.Anonymity
+		}
+		p.LastCheck = now
+	}
+	return proxies, skipped, nil
+}
+
+// ParseLine 解析单行格式的代理：ip:port、type://ip:port、type://user:pass@ip:port 或 ip:port:user:pass
+// 未给出类型时类型为空，由调用方使用默认类型或进行协议探测
+func ParseLine(line string) (*model.Proxy, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, fmt.Errorf("empty proxy")
+	}
+
+	if strings.Contains(line, "://") {
+		u, err := url.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		proxyType, err := ParseType(u.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		p := &model.Proxy{IP: u.Hostname(), Port: u.Port(), Type: proxyType}
+		if u.User != nil {
+			p.Username = u.User.Username()
+			p.Password, _ = u.User.Password()
+		}
+		return p, Normalize(p)
+	}
+
+	// IPv6 地址需要使用 [ip]:port 形式
+	if strings.HasPrefix(line, "[") {
+		host, port, err := net.SplitHostPort(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy address: %s", line)
+		}
+		p := &model.Proxy{IP: host, Port: port}
+		return p, Normalize(p)
+	}
+
+	parts := strings.Split(line, ":")
+	var p *model.Proxy
+	switch len(parts) {
+	case 2:
+		p = &model.Proxy{IP: parts[0], Port: parts[1]}
+	case 4:
+		p = &model.Proxy{IP: parts[0], Port: parts[1], Username: parts[2], Password: parts[3]}
+	default:
+		return nil, fmt.Errorf("invalid proxy address: %s", line)
+	}
+	return p, Normalize(p)
+}
+
+// ParseType 解析代理类型，兼容 socks5h、socks4a 等常见写法，"socks" 等无法确定版本的类型返回空
+func ParseType(s string) (model.ProxyType, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return "", nil
+	case "http":
+		return model.ProxyTypeHTTP, nil
+	case "https":
+		return model.ProxyTypeHTTPS, nil
+	case "socks4", "socks4a":
+		return model.ProxyTypeSOCKS4, nil
+	case "socks5", "socks5h":
+		return model.ProxyTypeSOCKS5, nil
+	case "socks":
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported proxy type: %s", s)
+	}
+}
+
+// Normalize 清理并校验代理的地址和类型
+func Normalize(p *model.Proxy) error {
+	p.IP = strings.Trim(strings.TrimSpace(p.IP), "[]")
+	p.Port = strings.TrimSpace(p.Port)
+	if p.IP == "" {
+		return fmt.Errorf("missing proxy host")
+	}
+	port, err := strconv.Atoi(p.Port)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid proxy port: %q", p.Port)
+	}
+	if p.Type != "" && !p.Type.IsValid() {
+		return fmt.Errorf("unsupported proxy type: %s", p.Type)
+	}
+	return nil
+}
+
+// detectFormat 根据内容识别格式：以 [ 开头为 JSON，首个有效行包含逗号为 CSV，否则为文本
+func detectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		return FormatJSON
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, ",") {
+			return FormatCSV
+		}
+		break
+	}
+	return FormatText
+}
+
+// parseText 逐行解析，忽略空行和 # 开头的注释，每行只取第一列
+func parseText(data []byte) ([]*model.Proxy, int) {
+	var proxies []*model.Proxy
+	var skipped int
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		p, err := ParseLine(fields[0])
+		if err != nil {
+			skipped++
+			continue
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, skipped
+}
+
+// parseCSV 解析 CSV，首行包含 ip 或 proxy 列时作为表头
+func parseCSV(data []byte) ([]*model.Proxy, int, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var columns map[string]int
+	var proxies []*model.Proxy
+	var skipped int
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid csv: %w", err)
+		}
+
+		if first {
+			if header := csvHeader(record); header != nil {
+				columns = header
+				continue
+			}
+		}
+
+		p, err := parseCSVRecord(record, columns)
+		if err != nil {
+			skipped++
+			continue
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, skipped, nil
+}
+
+// csvHeader 识别表头，返回列名到列序号的映射，不是表头时返回 nil
+func csvHeader(record []string) map[string]int {
+	columns := make(map[string]int, len(record))
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "host", "address":
+			name = "ip"
+		case "protocol", "scheme":
+			name = "type"
+		case "user":
+			name = "username"
+		case "pass":
+			name = "password"
+		case "addr", "url":
+			name = "proxy"
+		}
+		columns[name] = i
+	}
+
+	_, hasIP := columns["ip"]
+	_, hasProxy := columns["proxy"]
+	if !hasIP && !hasProxy {
+		return nil
+	}
+	return columns
+}
+
+func parseCSVRecord(record []string, columns map[string]int) (*model.Proxy, error) {
+	field := func(name string, index int) string {
+		if columns != nil {
+			i, ok := columns[name]
+			if !ok {
+				return ""
+			}
+			index = i
+		}
+		if index >= 0 && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	// 只有一列或表头中有 proxy 列时按单行格式解析
+	if line := field("proxy", -1); line != "" {
+		return ParseLine(line)
+	}
+	if columns == nil && len(record) == 1 {
+		return ParseLine(record[0])
+	}
+
+	return newProxy(field("ip", 0), field("port", 1), field("type", 2),
+		field("username", 3), field("password", 4), field("anonymity", 5))
+}
+
+// parseJSON 解析 JSON 数组，元素可以是代理对象或单行格式的字符串
+func parseJSON(data []byte) ([]*model.Proxy, int, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, 0, fmt.Errorf("invalid json: %w", err)
+	}
+
+	var proxies []*model.Proxy
+	var skipped int
+	for _, item := range items {
+		p, err := parseJSONItem(item)
+		if err != nil {
+			skipped++
+			continue
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, skipped, nil
+}
+
+func parseJSONItem(item json.RawMessage) (*model.Proxy, error) {
+	var line string
+	if err := json.Unmarshal(item, &line); err == nil {
+		return ParseLine(line)
+	}
+
+	var obj jsonProxy
+	if err := json.Unmarshal(item, &obj); err != nil {
+		return nil, err
+	}
+	if obj.Proxy != "" {
+		return ParseLine(obj.Proxy)
+	}
+	return newProxy(obj.IP, obj.Port.String(), obj.Type, obj.Username, obj.Password, obj.Anonymity)
+}
+
+// newProxy 由各字段构造代理并校验
+func newProxy(ip, port, proxyType, username, password, anonymity string) (*model.Proxy, error) {
+	t, err := ParseType(proxyType)
+	if err != nil {
+		return nil, err
+	}
+	p := &model.Proxy{
+		IP:        ip,
+		Port:      port,
+		Type:      t,
+		Username:  username,
+		Password:  password,
+		Anonymity: model.ParseAnonymityLevel(anonymity),
+	}
+	return p, Normalize(p)
+}
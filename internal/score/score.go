@@ -0,0 +1,65 @@
+package score
+
+import "github.com/langchou/proxyPool/internal/model"
+
+// 默认评分参数
+const (
+	defaultInitial   = 50
+	defaultMax       = 100
+	defaultIncrement = 10
+	defaultDecrement = 20
+)
+
+// Scorer 根据验证结果调整代理评分
+type Scorer struct {
+	initial   int
+	max       int
+	min       int
+	increment int
+	decrement int
+}
+
+func NewScorer(initial, max, min, increment, decrement int) *Scorer {
+	if max <= 0 {
+		max = defaultMax
+	}
+	if initial <= 0 {
+		initial = defaultInitial
+	}
+	if initial > max {
+		initial = max
+	}
+	if increment <= 0 {
+		increment = defaultIncrement
+	}
+	if decrement <= 0 {
+		decrement = defaultDecrement
+	}
+
+	return &Scorer{
+		initial:   initial,
+		max:       max,
+		min:       min,
+		increment: increment,
+		decrement: decrement,
+	}
+}
+
+// Initial 新代理的初始分数
+func (s *Scorer) Initial() int {
+	return s.initial
+}
+
+// Success 验证成功时加分，不超过上限
+func (s *Scorer) Success(p *model.Proxy) {
+	p.Score += s.increment
+	if p.Score > s.max {
+		p.Score = s.max
+	}
+}
+
+// Failure 验证失败时扣分，返回分数是否已降到淘汰线
+func (s *Scorer) Failure(p *model.Proxy) bool {
+	p.Score -= s.decrement
+	return p.Score <= s.min
+}
@@ -0,0 +1,608 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// bbolt 数据结构：
+//
+//	proxies                      key 为 ip:port，value 为 boltRecord JSON
+//	cursors                      轮询游标
+//	source_counts/{name}         代理源的小时计数，key 为小时的 Unix 时间戳，value 为 SourceCounts JSON
+//	idx:{name}/{value}           二级索引，嵌套 bucket 中的 key 为 ip:port
+var (
+	proxiesBucket      = []byte("proxies")
+	cursorsBucket      = []byte("cursors")
+	sourceCountsBucket = []byte("source_counts")
+)
+
+const (
+	indexBucketPrefix = "idx:"
+	indexType         = "type"
+	indexAnonymity    = "anonymity"
+	indexTarget       = "target"
+	indexCountry      = "country"
+	indexSource       = "source"
+)
+
+// boltRecord 保存在 bbolt 中的代理记录
+type boltRecord struct {
+	Proxy   *model.Proxy `json:"proxy"`
+	Expires time.Time    `json:"expires"`
+}
+
+// boltStatsRecord 统计时只解析记录中的评分、速度、来源和过期时间
+type boltStatsRecord struct {
+	Proxy struct {
+		Score     int       `json:"score"`
+		Speed     int64     `json:"speed"`
+		Source    string    `json:"source"`
+		FirstSeen time.Time `json:"first_seen"`
+	} `json:"proxy"`
+	Expires time.Time `json:"expires"`
+}
+
+// boltIndex 代理所在的一个索引项
+type boltIndex struct {
+	name  string
+	value string
+}
+
+// BoltStorage 基于 bbolt 文件的存储，适用于不需要 Redis 的单机部署，重启后数据保留
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create storage directory failed: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt database failed: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{proxiesBucket, cursorsBucket, sourceCountsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create bolt buckets failed: %w", err)
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+// Close 关闭数据库文件
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStorage) Save(ctx context.Context, proxy *model.Proxy) error {
+	key := []byte(proxy.IP + ":" + proxy.Port)
+	logger.Log.Debug("Saving proxy to bolt", zap.ByteString("key", key))
+
+	data, err := json.Marshal(&boltRecord{
+		Proxy:   proxy,
+		Expires: time.Now().Add(proxyTTL), // 24 小时未更新则过期
+	})
+	if err != nil {
+		logger.Log.Error("Failed to marshal proxy", zap.Error(err))
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(proxiesBucket)
+
+		// 代理类型等字段可能变化，先从旧索引中移除
+		if old, err := decodeRecord(b.Get(key)); err == nil && old != nil {
+			if err := removeIndexes(tx, key, old.Proxy); err != nil {
+				return err
+			}
+		}
+
+		if err := b.Put(key, data); err != nil {
+			return err
+		}
+		return addIndexes(tx, key, proxy)
+	})
+}
+
+func (s *BoltStorage) Get(ctx context.Context, key string) (*model.Proxy, error) {
+	var proxy *model.Proxy
+	err := s.db.View(func(tx *bolt.Tx) error {
+		record, err := decodeRecord(tx.Bucket(proxiesBucket).Get([]byte(key)))
+		if err != nil {
+			return err
+		}
+		if record == nil || record.expired(time.Now()) {
+			return ErrNotFound
+		}
+		proxy = record.Proxy
+		return nil
+	})
+	return proxy, err
+}
+
+func (s *BoltStorage) GetAll(ctx context.Context) ([]*model.Proxy, error) {
+	return s.Query(ctx, Filter{})
+}
+
+func (s *BoltStorage) GetRandom(ctx context.Context) (*model.Proxy, error) {
+	var proxy *model.Proxy
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(proxiesBucket)
+		n := b.Stats().KeyN
+		if n == 0 {
+			return ErrNotFound
+		}
+
+		c := b.Cursor()
+		k, v := c.First()
+		for i := rand.Intn(n); i > 0; i-- {
+			k, v = c.Next()
+		}
+
+		// 从随机位置开始遍历一圈，跳过已过期的代理
+		now := time.Now()
+		for i := 0; i < n; i++ {
+			if k == nil {
+				k, v = c.First()
+			}
+			if record, err := decodeRecord(v); err == nil && record != nil && !record.expired(now) {
+				proxy = record.Proxy
+				return nil
+			}
+			k, v = c.Next()
+		}
+		return ErrNotFound
+	})
+	return proxy, err
+}
+
+// Query 按条件查询代理，类型、匿名级别、验证目标、出口国家和来源通过索引 bucket 过滤
+func (s *BoltStorage) Query(ctx context.Context, filter Filter) ([]*model.Proxy, error) {
+	var proxies []*model.Proxy
+	var expired [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(proxiesBucket)
+		now := time.Now()
+
+		collect := func(k, v []byte) {
+			record, err := decodeRecord(v)
+			if err != nil {
+				logger.Log.Error("Failed to unmarshal proxy", zap.ByteString("key", k), zap.Error(err))
+				return
+			}
+			if record == nil {
+				return
+			}
+			if record.expired(now) {
+				expired = append(expired, append([]byte(nil), k...))
+				return
+			}
+			if !filter.Match(record.Proxy) {
+				return
+			}
+			proxies = append(proxies, record.Proxy)
+		}
+
+		keys := candidateKeys(tx, filter)
+		if keys == nil {
+			return b.ForEach(func(k, v []byte) error {
+				collect(k, v)
+				return nil
+			})
+		}
+
+		for key := range keys {
+			collect([]byte(key), b.Get([]byte(key)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeExpired(expired)
+	return filter.apply(proxies), nil
+}
+
+func (s *BoltStorage) Remove(ctx context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return removeProxy(tx, []byte(key))
+	})
+}
+
+func (s *BoltStorage) UpdateStatus(ctx context.Context, proxy *model.Proxy) error {
+	key := proxy.IP + ":" + proxy.Port
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(proxiesBucket)
+		record, err := decodeRecord(b.Get([]byte(key)))
+		if err != nil {
+			return err
+		}
+		if record == nil || record.expired(time.Now()) {
+			return ErrNotFound
+		}
+
+		record.Proxy.Score = proxy.Score
+		record.Proxy.History = proxy.History
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+}
+
+// IncrCursor 增加指定游标并返回增加后的值，用于轮询选择代理
+func (s *BoltStorage) IncrCursor(ctx context.Context, name string, delta int64) (int64, error) {
+	var value int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(cursorsBucket)
+		if data := b.Get([]byte(name)); len(data) == 8 {
+			value = int64(binary.BigEndian.Uint64(data))
+		}
+		value += delta
+
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(value))
+		return b.Put([]byte(name), buf)
+	})
+	return value, err
+}
+
+// Stats 计算代理池统计，类型、匿名级别和国家的数量取自索引 bucket，评分、速度和来源只解析记录中的相应字段
+func (s *BoltStorage) Stats(ctx context.Context) (*PoolStats, error) {
+	stats := newPoolStats()
+	var speeds []int64
+	var expired [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		now := time.Now()
+		return tx.Bucket(proxiesBucket).ForEach(func(k, v []byte) error {
+			var record boltStatsRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				logger.Log.Error("Failed to unmarshal proxy", zap.ByteString("key", k), zap.Error(err))
+				return nil
+			}
+			if now.After(record.Expires) {
+				expired = append(expired, append([]byte(nil), k...))
+				return nil
+			}
+			stats.Total++
+			stats.BySource[labelOrUnknown(record.Proxy.Source)]++
+			stats.ByScore[scoreBand(record.Proxy.Score)].Count++
+			speeds = append(speeds, record.Proxy.Speed)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 先删除过期代理，保证索引中的数量与总数一致
+	s.removeExpired(expired)
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		for _, t := range statsTypes {
+			stats.ByType[string(t)] = countIndexKeys(tx, indexType, string(t))
+		}
+		known := 0
+		for _, level := range statsAnonymity {
+			n := countIndexKeys(tx, indexAnonymity, string(level))
+			stats.ByAnonymity[string(level)] = n
+			known += n
+		}
+		stats.ByAnonymity[unknownLabel] = max(stats.Total-known, 0)
+
+		known = 0
+		if parent := tx.Bucket([]byte(indexBucketPrefix + indexCountry)); parent != nil {
+			err := parent.ForEach(func(k, v []byte) error {
+				if n := countIndexKeys(tx, indexCountry, string(k)); n > 0 {
+					stats.ByCountry[string(k)] = n
+					known += n
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if stats.Total > known {
+			stats.ByCountry[unknownLabel] = stats.Total - known
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats.setSpeeds(speeds)
+	return stats, nil
+}
+
+// AddSourceCounts 累加代理源当前小时的计数，同时清理超过保留时间的计数
+func (s *BoltStorage) AddSourceCounts(ctx context.Context, name string, counts SourceCounts) error {
+	now := time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(sourceCountsBucket).CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+
+		// key 按时间顺序排列，从头收集过期的计数后删除
+		cutoff := sourceHourCutoff(now)
+		var expired [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && int64(binary.BigEndian.Uint64(k)) < cutoff; k, _ = c.Next() {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, uint64(sourceHour(now)))
+		var current SourceCounts
+		if data := b.Get(key); data != nil {
+			if err := json.Unmarshal(data, &current); err != nil {
+				return err
+			}
+		}
+		current.add(counts)
+
+		data, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, data)
+	})
+}
+
+// SourceStats 汇总代理源最近的计数和代理池中各代理源代理的存活情况，代理记录只解析来源相关字段
+func (s *BoltStorage) SourceStats(ctx context.Context) ([]SourceStats, error) {
+	builder := newSourceStatsBuilder(time.Now())
+	err := s.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(sourceCountsBucket).ForEach(func(name, _ []byte) error {
+			b := tx.Bucket(sourceCountsBucket).Bucket(name)
+			if b == nil {
+				return nil
+			}
+			return b.ForEach(func(k, v []byte) error {
+				var counts SourceCounts
+				if len(k) != 8 || json.Unmarshal(v, &counts) != nil {
+					return nil
+				}
+				builder.addCounts(string(name), int64(binary.BigEndian.Uint64(k)), counts)
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Bucket(proxiesBucket).ForEach(func(k, v []byte) error {
+			var record boltStatsRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return nil
+			}
+			if record.Proxy.Source != "" && !now.After(record.Expires) {
+				builder.addProxy(record.Proxy.Source, record.Proxy.FirstSeen)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return builder.result(), nil
+}
+
+// removeExpired 删除查询过程中发现的过期代理
+func (s *BoltStorage) removeExpired(keys [][]byte) {
+	if len(keys) == 0 {
+		return
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, key := range keys {
+			if err := removeProxy(tx, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Log.Error("Failed to remove expired proxies", zap.Error(err))
+	}
+}
+
+// removeProxy 在事务中删除代理及其索引
+func removeProxy(tx *bolt.Tx, key []byte) error {
+	b := tx.Bucket(proxiesBucket)
+	if record, err := decodeRecord(b.Get(key)); err == nil && record != nil {
+		if err := removeIndexes(tx, key, record.Proxy); err != nil {
+			return err
+		}
+	}
+	return b.Delete(key)
+}
+
+// candidateKeys 根据索引计算满足类型、匿名级别、验证目标、出口国家和来源条件的代理，返回 nil 表示不限制
+func candidateKeys(tx *bolt.Tx, filter Filter) map[string]bool {
+	var candidates map[string]bool
+
+	intersect := func(keys map[string]bool) {
+		if candidates == nil {
+			candidates = keys
+			return
+		}
+		for k := range candidates {
+			if !keys[k] {
+				delete(candidates, k)
+			}
+		}
+	}
+
+	if len(filter.Types) > 0 {
+		keys := make(map[string]bool)
+		for _, t := range filter.Types {
+			collectIndexKeys(tx, indexType, string(t), keys)
+		}
+		intersect(keys)
+	}
+	if len(filter.Anonymity) > 0 {
+		keys := make(map[string]bool)
+		for _, level := range filter.Anonymity {
+			collectIndexKeys(tx, indexAnonymity, string(level), keys)
+		}
+		intersect(keys)
+	}
+	if filter.Target != "" {
+		keys := make(map[string]bool)
+		collectIndexKeys(tx, indexTarget, filter.Target, keys)
+		intersect(keys)
+	}
+	if len(filter.Countries) > 0 {
+		keys := make(map[string]bool)
+		for _, country := range filter.Countries {
+			collectIndexKeys(tx, indexCountry, country, keys)
+		}
+		intersect(keys)
+	}
+	if len(filter.Sources) > 0 {
+		keys := make(map[string]bool)
+		for _, source := range filter.Sources {
+			collectIndexKeys(tx, indexSource, source, keys)
+		}
+		intersect(keys)
+	}
+	return candidates
+}
+
+// collectIndexKeys 将索引中的代理加入 keys
+func collectIndexKeys(tx *bolt.Tx, name, value string, keys map[string]bool) {
+	parent := tx.Bucket([]byte(indexBucketPrefix + name))
+	if parent == nil {
+		return
+	}
+	b := parent.Bucket([]byte(value))
+	if b == nil {
+		return
+	}
+	b.ForEach(func(k, _ []byte) error {
+		keys[string(k)] = true
+		return nil
+	})
+}
+
+// countIndexKeys 返回索引中的代理数量
+func countIndexKeys(tx *bolt.Tx, name, value string) int {
+	parent := tx.Bucket([]byte(indexBucketPrefix + name))
+	if parent == nil {
+		return 0
+	}
+	b := parent.Bucket([]byte(value))
+	if b == nil {
+		return 0
+	}
+	return b.Stats().KeyN
+}
+
+// proxyIndexes 返回代理应当加入的索引
+func proxyIndexes(proxy *model.Proxy) []boltIndex {
+	var indexes []boltIndex
+	for _, t := range proxy.SupportedTypes() {
+		indexes = append(indexes, boltIndex{name: indexType, value: string(t)})
+	}
+	if proxy.Anonymity.IsValid() {
+		indexes = append(indexes, boltIndex{name: indexAnonymity, value: string(proxy.Anonymity)})
+	}
+	for _, target := range proxy.Targets {
+		indexes = append(indexes, boltIndex{name: indexTarget, value: target})
+	}
+	if proxy.Country != "" {
+		indexes = append(indexes, boltIndex{name: indexCountry, value: proxy.Country})
+	}
+	if proxy.Source != "" {
+		indexes = append(indexes, boltIndex{name: indexSource, value: proxy.Source})
+	}
+	return indexes
+}
+
+func addIndexes(tx *bolt.Tx, key []byte, proxy *model.Proxy) error {
+	for _, index := range proxyIndexes(proxy) {
+		parent, err := tx.CreateBucketIfNotExists([]byte(indexBucketPrefix + index.name))
+		if err != nil {
+			return err
+		}
+		b, err := parent.CreateBucketIfNotExists([]byte(index.value))
+		if err != nil {
+			return err
+		}
+		if err := b.Put(key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeIndexes(tx *bolt.Tx, key []byte, proxy *model.Proxy) error {
+	for _, index := range proxyIndexes(proxy) {
+		parent := tx.Bucket([]byte(indexBucketPrefix + index.name))
+		if parent == nil {
+			continue
+		}
+		b := parent.Bucket([]byte(index.value))
+		if b == nil {
+			continue
+		}
+		if err := b.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeRecord 解析代理记录，data 为空时返回 nil
+func decodeRecord(data []byte) (*boltRecord, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var record boltRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	if record.Proxy == nil {
+		return nil, fmt.Errorf("invalid proxy record")
+	}
+	return &record, nil
+}
+
+func (r *boltRecord) expired(now time.Time) bool {
+	return now.After(r.Expires)
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// testBackends 创建用于对比结果的内存存储和临时文件中的 bbolt 存储
+func testBackends(t *testing.T) map[string]Storage {
+	t.Helper()
+
+	bolt, err := NewBoltStorage(filepath.Join(t.TempDir(), "proxy.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+
+	return map[string]Storage{
+		"memory": NewMemoryStorage(),
+		"bolt":   bolt,
+	}
+}
+
+// TestFilterBackends 同一过滤条件在不同存储后端上的结果应一致
+func TestFilterBackends(t *testing.T) {
+	ctx := context.Background()
+	backends := testBackends(t)
+
+	proxies := []*model.Proxy{
+		{IP: "1.1.1.1", Port: "80", Type: model.ProxyTypeHTTP, Score: 90, Speed: 300, Anonymity: model.AnonymityElite,
+			GeoInfo: model.GeoInfo{Country: "CN", ASN: 4134}, Source: "kuaidaili"},
+		{IP: "2.2.2.2", Port: "80", Type: model.ProxyTypeHTTP, Score: 70, Speed: 100, Anonymity: model.AnonymityAnonymous,
+			GeoInfo: model.GeoInfo{Country: "US", ASN: 15169}, Source: "admin", Targets: []string{"google"}},
+		{IP: "3.3.3.3", Port: "80", Type: model.ProxyTypeSOCKS5, Score: 50, Anonymity: model.AnonymityElite,
+			GeoInfo: model.GeoInfo{Country: "US", ASN: 7922}, Source: "kuaidaili", Targets: []string{"google"}},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"best", Filter{OrderBy: OrderByScore, Limit: 1}, []string{"1.1.1.1"}},
+		{"best excluding top country", Filter{Excluded: []string{"CN"}, OrderBy: OrderByScore, Limit: 1}, []string{"2.2.2.2"}},
+		{"fastest by asn", Filter{ASNs: []uint{7922, 4134}, OrderBy: OrderBySpeed, Limit: 1}, []string{"1.1.1.1"}},
+		{"fastest unmeasured last", Filter{Countries: []string{"US"}, OrderBy: OrderBySpeed}, []string{"2.2.2.2", "3.3.3.3"}},
+		{"type", Filter{Types: []model.ProxyType{model.ProxyTypeSOCKS5}}, []string{"3.3.3.3"}},
+		{"anonymity and source", Filter{Anonymity: []model.AnonymityLevel{model.AnonymityElite}, Sources: []string{"kuaidaili"}, OrderBy: OrderByScore},
+			[]string{"1.1.1.1", "3.3.3.3"}},
+		{"target", Filter{Target: "google", OrderBy: OrderByScore}, []string{"2.2.2.2", "3.3.3.3"}},
+		{"random by type", Filter{Types: []model.ProxyType{model.ProxyTypeSOCKS5}, OrderBy: OrderByRandom, Limit: 1}, []string{"3.3.3.3"}},
+		{"random excluding country", Filter{Excluded: []string{"US"}, OrderBy: OrderByRandom, Limit: 2}, []string{"1.1.1.1"}},
+	}
+
+	for name, s := range backends {
+		for _, p := range proxies {
+			if err := s.Save(ctx, p); err != nil {
+				t.Fatalf("%s: Save() error = %v", name, err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				got, err := s.Query(ctx, tt.filter)
+				if err != nil {
+					t.Fatalf("Query() error = %v", err)
+				}
+				if !sameIPs(got, tt.want) {
+					t.Errorf("Query() = %v, want %v", ips(got), tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestStatsBackends 不同存储后端的统计结果应一致
+func TestStatsBackends(t *testing.T) {
+	ctx := context.Background()
+	backends := testBackends(t)
+
+	proxies := []*model.Proxy{
+		{IP: "1.1.1.1", Port: "80", Type: model.ProxyTypeHTTP, Score: 90, Speed: 300, Anonymity: model.AnonymityElite,
+			GeoInfo: model.GeoInfo{Country: "CN"}, Source: "kuaidaili"},
+		{IP: "2.2.2.2", Port: "80", Type: model.ProxyTypeSOCKS5, Score: 30, Speed: 100,
+			GeoInfo: model.GeoInfo{Country: "US"}},
+		{IP: "3.3.3.3", Port: "80", Type: model.ProxyTypeSOCKS5, Score: 50, Anonymity: model.AnonymityElite,
+			GeoInfo: model.GeoInfo{Country: "US"}, Source: "kuaidaili"},
+		{IP: "4.4.4.4", Port: "80", Type: model.ProxyTypeSOCKS4, Score: 10},
+	}
+
+	for name, s := range backends {
+		for _, p := range proxies {
+			if err := s.Save(ctx, p); err != nil {
+				t.Fatalf("%s: Save() error = %v", name, err)
+			}
+		}
+
+		stats, err := s.Stats(ctx)
+		if err != nil {
+			t.Fatalf("%s: Stats() error = %v", name, err)
+		}
+		if stats.Total != 4 {
+			t.Errorf("%s: Total = %d, want 4", name, stats.Total)
+		}
+		checkCounts(t, name+" ByType", stats.ByType, map[string]int{"http": 1, "https": 0, "socks4": 1, "socks5": 2})
+		checkCounts(t, name+" ByAnonymity", stats.ByAnonymity, map[string]int{"transparent": 0, "anonymous": 0, "elite": 2, unknownLabel: 2})
+		checkCounts(t, name+" ByCountry", stats.ByCountry, map[string]int{"CN": 1, "US": 2, unknownLabel: 1})
+		checkCounts(t, name+" BySource", stats.BySource, map[string]int{"kuaidaili": 2, unknownLabel: 2})
+		if stats.Speed.Measured != 2 || stats.Speed.Average != 200 {
+			t.Errorf("%s: Speed = %+v, want 2 measured with average 200", name, stats.Speed)
+		}
+	}
+}
+
+func checkCounts(t *testing.T, name string, got, want map[string]int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+		return
+	}
+	for k, n := range want {
+		if got[k] != n {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
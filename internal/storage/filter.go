@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// 排序方式
+const (
+	OrderByScore  = "score"  // 按评分从高到低
+	OrderBySpeed  = "speed"  // 按响应速度从快到慢，未测速的排在最后
+	OrderByRandom = "random" // 随机顺序
+)
+
+// Filter 代理查询条件
+type Filter struct {
+	Types     []model.ProxyType      // 代理类型（按代理实际支持的类型匹配），多个类型取并集
+	Anonymity []model.AnonymityLevel // 匿名级别，多个级别取并集
+	Target    string                 // 只返回通过该验证目标的代理，为空时不限制
+	Countries []string               // 出口 IP 所在国家代码（大写），多个国家取并集
+	Excluded  []string               // 排除的出口国家代码（大写）
+	ASNs      []uint                 // 出口 IP 所属自治系统号，多个取并集
+	Sources   []string               // 代理来源的代理源名称，多个取并集
+	OrderBy   string                 // 排序方式，为空时不保证顺序
+	Limit     int                    // 返回数量上限，0 表示不限制
+}
+
+// Match 检查代理是否满足过滤条件（不考虑排序和数量）
+func (f Filter) Match(p *model.Proxy) bool {
+	// 类型过滤
+	if len(f.Types) > 0 {
+		typeMatched := false
+		for _, t := range f.Types {
+			if p.HasType(t) {
+				typeMatched = true
+				break
+			}
+		}
+		if !typeMatched {
+			return false
+		}
+	}
+
+	// 匿名级别过滤
+	if len(f.Anonymity) > 0 {
+		levelMatched := false
+		for _, level := range f.Anonymity {
+			if p.Anonymity == level {
+				levelMatched = true
+				break
+			}
+		}
+		if !levelMatched {
+			return false
+		}
+	}
+
+	// 验证目标过滤
+	if f.Target != "" && !p.PassedTarget(f.Target) {
+		return false
+	}
+
+	// 出口地理位置过滤
+	if len(f.Countries) > 0 && !containsString(f.Countries, p.Country) {
+		return false
+	}
+	if len(f.Excluded) > 0 && containsString(f.Excluded, p.Country) {
+		return false
+	}
+	if len(f.ASNs) > 0 {
+		asnMatched := false
+		for _, asn := range f.ASNs {
+			if p.ASN == asn {
+				asnMatched = true
+				break
+			}
+		}
+		if !asnMatched {
+			return false
+		}
+	}
+
+	// 来源过滤
+	if len(f.Sources) > 0 && !containsString(f.Sources, p.Source) {
+		return false
+	}
+
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 在内存中对代理列表进行过滤、排序和截取
+func (f Filter) apply(proxies []*model.Proxy) []*model.Proxy {
+	result := make([]*model.Proxy, 0, len(proxies))
+	for _, p := range proxies {
+		if f.Match(p) {
+			result = append(result, p)
+		}
+	}
+
+	switch f.OrderBy {
+	case OrderByScore:
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Score > result[j].Score
+		})
+	case OrderBySpeed:
+		sort.SliceStable(result, func(i, j int) bool {
+			// 未测速（速度为 0）的代理排在最后
+			if result[i].Speed <= 0 {
+				return false
+			}
+			if result[j].Speed <= 0 {
+				return true
+			}
+			return result[i].Speed < result[j].Speed
+		})
+	case OrderByRandom:
+		rand.Shuffle(len(result), func(i, j int) {
+			result[i], result[j] = result[j], result[i]
+		})
+	}
+
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[:f.Limit]
+	}
+	return result
+}
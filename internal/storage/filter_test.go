@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+func TestFilterMatch(t *testing.T) {
+	proxy := &model.Proxy{
+		IP:              "1.2.3.4",
+		Port:            "8080",
+		Type:            model.ProxyTypeHTTP,
+		SupportsConnect: true,
+		Anonymity:       model.AnonymityElite,
+		Targets:         []string{"google"},
+		GeoInfo:         model.GeoInfo{Country: "US", ASN: 15169},
+		Source:          "kuaidaili",
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"supported type", Filter{Types: []model.ProxyType{model.ProxyTypeHTTPS}}, true},
+		{"unsupported type", Filter{Types: []model.ProxyType{model.ProxyTypeHTTP}}, false},
+		{"type union", Filter{Types: []model.ProxyType{model.ProxyTypeSOCKS5, model.ProxyTypeHTTPS}}, true},
+		{"anonymity", Filter{Anonymity: []model.AnonymityLevel{model.AnonymityElite}}, true},
+		{"other anonymity", Filter{Anonymity: []model.AnonymityLevel{model.AnonymityTransparent}}, false},
+		{"passed target", Filter{Target: "google"}, true},
+		{"other target", Filter{Target: "github"}, false},
+		{"country", Filter{Countries: []string{"CN", "US"}}, true},
+		{"other country", Filter{Countries: []string{"CN"}}, false},
+		{"excluded country", Filter{Excluded: []string{"US"}}, false},
+		{"other excluded country", Filter{Excluded: []string{"CN"}}, true},
+		{"asn", Filter{ASNs: []uint{4134, 15169}}, true},
+		{"other asn", Filter{ASNs: []uint{4134}}, false},
+		{"source", Filter{Sources: []string{"kuaidaili"}}, true},
+		{"other source", Filter{Sources: []string{"admin"}}, false},
+		{"all conditions", Filter{
+			Types:     []model.ProxyType{model.ProxyTypeHTTPS},
+			Anonymity: []model.AnonymityLevel{model.AnonymityElite},
+			Target:    "google",
+			Countries: []string{"US"},
+			ASNs:      []uint{15169},
+			Sources:   []string{"kuaidaili"},
+		}, true},
+		{"one condition fails", Filter{
+			Types:     []model.ProxyType{model.ProxyTypeHTTPS},
+			Countries: []string{"US"},
+			Excluded:  []string{"US"},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(proxy); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	proxies := []*model.Proxy{
+		{IP: "1.1.1.1", Port: "80", Type: model.ProxyTypeHTTP, Score: 50, Speed: 300},
+		{IP: "2.2.2.2", Port: "80", Type: model.ProxyTypeSOCKS5, Score: 90, Speed: 0},
+		{IP: "3.3.3.3", Port: "80", Type: model.ProxyTypeHTTP, Score: 70, Speed: 100},
+		{IP: "4.4.4.4", Port: "80", Type: model.ProxyTypeHTTP, Score: 10, Speed: 200},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"by score", Filter{OrderBy: OrderByScore}, []string{"2.2.2.2", "3.3.3.3", "1.1.1.1", "4.4.4.4"}},
+		{"by speed, unmeasured last", Filter{OrderBy: OrderBySpeed}, []string{"3.3.3.3", "4.4.4.4", "1.1.1.1", "2.2.2.2"}},
+		{"limit", Filter{OrderBy: OrderByScore, Limit: 2}, []string{"2.2.2.2", "3.3.3.3"}},
+		{"limit larger than result", Filter{OrderBy: OrderBySpeed, Limit: 10}, []string{"3.3.3.3", "4.4.4.4", "1.1.1.1", "2.2.2.2"}},
+		{"filter before limit", Filter{Types: []model.ProxyType{model.ProxyTypeHTTP}, OrderBy: OrderByScore, Limit: 1}, []string{"3.3.3.3"}},
+		{"no order keeps input order", Filter{Types: []model.ProxyType{model.ProxyTypeHTTP}}, []string{"1.1.1.1", "3.3.3.3", "4.4.4.4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]*model.Proxy(nil), proxies...)
+			got := tt.filter.apply(input)
+			if !sameIPs(got, tt.want) {
+				t.Errorf("apply() = %v, want %v", ips(got), tt.want)
+			}
+		})
+	}
+}
+
+func ips(proxies []*model.Proxy) []string {
+	result := make([]string, len(proxies))
+	for i, p := range proxies {
+		result[i] = p.IP
+	}
+	return result
+}
+
+func sameIPs(proxies []*model.Proxy, want []string) bool {
+	if len(proxies) != len(want) {
+		return false
+	}
+	for i, p := range proxies {
+		if p.IP != want[i] {
+			return false
+		}
+	}
+	return true
+}
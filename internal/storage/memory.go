@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+type memoryEntry struct {
+	proxy   model.Proxy
+	expires time.Time
+}
+
+// MemoryStorage 基于内存的存储，适用于单机部署和测试，重启后数据丢失
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	proxies map[string]*memoryEntry
+	cursors map[string]int64
+	sources map[string]map[int64]SourceCounts // 代理源名称 -> 小时 -> 计数
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		proxies: make(map[string]*memoryEntry),
+		cursors: make(map[string]int64),
+		sources: make(map[string]map[int64]SourceCounts),
+	}
+}
+
+func (s *MemoryStorage) Save(ctx context.Context, proxy *model.Proxy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.proxies[proxy.IP+":"+proxy.Port] = &memoryEntry{
+		proxy:   cloneProxy(proxy),
+		expires: time.Now().Add(proxyTTL), // 24 小时未更新则过期
+	}
+	return nil
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, key string) (*model.Proxy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.proxies[key]
+	if !ok || entry.expired(time.Now()) {
+		return nil, ErrNotFound
+	}
+
+	proxy := cloneProxy(&entry.proxy)
+	return &proxy, nil
+}
+
+func (s *MemoryStorage) GetAll(ctx context.Context) ([]*model.Proxy, error) {
+	return s.Query(ctx, Filter{})
+}
+
+func (s *MemoryStorage) GetRandom(ctx context.Context) (*model.Proxy, error) {
+	proxies, err := s.Query(ctx, Filter{})
+	if err != nil {
+		return nil, err
+	}
+	if len(proxies) == 0 {
+		return nil, ErrNotFound
+	}
+	return proxies[rand.Intn(len(proxies))], nil
+}
+
+func (s *MemoryStorage) Query(ctx context.Context, filter Filter) ([]*model.Proxy, error) {
+	s.purgeExpired()
+
+	s.mu.RLock()
+	proxies := make([]*model.Proxy, 0, len(s.proxies))
+	for _, entry := range s.proxies {
+		if !filter.Match(&entry.proxy) {
+			continue
+		}
+		proxy := cloneProxy(&entry.proxy)
+		proxies = append(proxies, &proxy)
+	}
+	s.mu.RUnlock()
+
+	return filter.apply(proxies), nil
+}
+
+func (s *MemoryStorage) Remove(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.proxies, key)
+	return nil
+}
+
+func (s *MemoryStorage) UpdateStatus(ctx context.Context, proxy *model.Proxy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.proxies[proxy.IP+":"+proxy.Port]
+	if !ok || entry.expired(time.Now()) {
+		return ErrNotFound
+	}
+
+	entry.proxy.Score = proxy.Score
+	entry.proxy.History = append([]model.CheckRecord(nil), proxy.History...)
+	return nil
+}
+
+// IncrCursor 增加指定游标并返回增加后的值，用于轮询选择代理
+func (s *MemoryStorage) IncrCursor(ctx context.Context, name string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursors[name] += delta
+	return s.cursors[name], nil
+}
+
+// Stats 直接遍历内存中的代理计算统计，不复制代理
+func (s *MemoryStorage) Stats(ctx context.Context) (*PoolStats, error) {
+	s.purgeExpired()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := newPoolStats()
+	speeds := make([]int64, 0, len(s.proxies))
+	for _, entry := range s.proxies {
+		stats.add(&entry.proxy)
+		speeds = append(speeds, entry.proxy.Speed)
+	}
+	stats.setSpeeds(speeds)
+	return stats, nil
+}
+
+// AddSourceCounts 累加代理源当前小时的计数，同时清理超过保留时间的计数
+func (s *MemoryStorage) AddSourceCounts(ctx context.Context, name string, counts SourceCounts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	hours, ok := s.sources[name]
+	if !ok {
+		hours = make(map[int64]SourceCounts)
+		s.sources[name] = hours
+	}
+	cutoff := sourceHourCutoff(now)
+	for hour := range hours {
+		if hour < cutoff {
+			delete(hours, hour)
+		}
+	}
+
+	hour := sourceHour(now)
+	current := hours[hour]
+	current.add(counts)
+	hours[hour] = current
+	return nil
+}
+
+// SourceStats 汇总代理源最近的计数和代理池中各代理源代理的存活情况
+func (s *MemoryStorage) SourceStats(ctx context.Context) ([]SourceStats, error) {
+	s.purgeExpired()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	builder := newSourceStatsBuilder(time.Now())
+	for name, hours := range s.sources {
+		for hour, counts := range hours {
+			builder.addCounts(name, hour, counts)
+		}
+	}
+	for _, entry := range s.proxies {
+		if entry.proxy.Source != "" {
+			builder.addProxy(entry.proxy.Source, entry.proxy.FirstSeen)
+		}
+	}
+	return builder.result(), nil
+}
+
+// purgeExpired 清理超过有效期未更新的代理
+func (s *MemoryStorage) purgeExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.proxies {
+		if entry.expired(now) {
+			delete(s.proxies, key)
+		}
+	}
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return now.After(e.expires)
+}
+
+// cloneProxy 复制代理，避免调用方修改存储中的数据
+func cloneProxy(p *model.Proxy) model.Proxy {
+	proxy := *p
+	proxy.Targets = append([]string(nil), p.Targets...)
+	proxy.Protocols = append([]model.ProxyType(nil), p.Protocols...)
+	proxy.History = append([]model.CheckRecord(nil), p.History...)
+	return proxy
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+func TestMemoryStorageSaveGet(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorage()
+
+	proxy := &model.Proxy{
+		IP:        "1.1.1.1",
+		Port:      "80",
+		Type:      model.ProxyTypeHTTP,
+		Score:     60,
+		Targets:   []string{"google"},
+		Protocols: []model.ProxyType{model.ProxyTypeHTTP, model.ProxyTypeSOCKS5},
+		History:   []model.CheckRecord{{Success: true}},
+	}
+	if err := s.Save(ctx, proxy); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := s.Get(ctx, "1.1.1.1:80")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Score != 60 || got.Type != model.ProxyTypeHTTP {
+		t.Errorf("Get() = %+v", got)
+	}
+
+	// 修改保存时传入的代理或返回的代理都不影响存储中的数据
+	proxy.Targets[0] = "github"
+	proxy.Protocols[1] = model.ProxyTypeSOCKS4
+	got.Targets[0] = "github"
+	got.Protocols[1] = model.ProxyTypeSOCKS4
+	got.History[0].Success = false
+	got.Score = 0
+	again, _ := s.Get(ctx, "1.1.1.1:80")
+	if again.Score != 60 || again.Targets[0] != "google" || again.Protocols[1] != model.ProxyTypeSOCKS5 || !again.History[0].Success {
+		t.Errorf("stored proxy modified by caller: %+v", again)
+	}
+
+	if _, err := s.Get(ctx, "2.2.2.2:80"); err != ErrNotFound {
+		t.Errorf("Get() missing proxy error = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Remove(ctx, "1.1.1.1:80"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := s.Get(ctx, "1.1.1.1:80"); err != ErrNotFound {
+		t.Errorf("Get() removed proxy error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStorageQuery(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorage()
+	for _, p := range []*model.Proxy{
+		{IP: "1.1.1.1", Port: "80", Type: model.ProxyTypeHTTP, Score: 50, Speed: 300},
+		{IP: "2.2.2.2", Port: "80", Type: model.ProxyTypeSOCKS5, Score: 90},
+		{IP: "3.3.3.3", Port: "80", Type: model.ProxyTypeHTTP, Score: 70, Speed: 100},
+	} {
+		if err := s.Save(ctx, p); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"by score", Filter{OrderBy: OrderByScore}, []string{"2.2.2.2", "3.3.3.3", "1.1.1.1"}},
+		{"by speed", Filter{OrderBy: OrderBySpeed}, []string{"3.3.3.3", "1.1.1.1", "2.2.2.2"}},
+		{"type with limit", Filter{Types: []model.ProxyType{model.ProxyTypeHTTP}, OrderBy: OrderByScore, Limit: 1}, []string{"3.3.3.3"}},
+		{"no match", Filter{Types: []model.ProxyType{model.ProxyTypeSOCKS4}}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if !sameIPs(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", ips(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStorageUpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorage()
+	s.Save(ctx, &model.Proxy{IP: "1.1.1.1", Port: "80", Type: model.ProxyTypeHTTP, Score: 60, Speed: 100})
+
+	update := &model.Proxy{
+		IP:      "1.1.1.1",
+		Port:    "80",
+		Score:   40,
+		Speed:   999,
+		History: []model.CheckRecord{{Success: false}},
+	}
+	if err := s.UpdateStatus(ctx, update); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	got, _ := s.Get(ctx, "1.1.1.1:80")
+	if got.Score != 40 || len(got.History) != 1 {
+		t.Errorf("UpdateStatus() did not update score and history: %+v", got)
+	}
+	if got.Speed != 100 {
+		t.Errorf("UpdateStatus() updated speed to %d, want 100", got.Speed)
+	}
+
+	if err := s.UpdateStatus(ctx, &model.Proxy{IP: "2.2.2.2", Port: "80"}); err != ErrNotFound {
+		t.Errorf("UpdateStatus() missing proxy error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStorageExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorage()
+	s.Save(ctx, &model.Proxy{IP: "1.1.1.1", Port: "80", Type: model.ProxyTypeHTTP})
+	s.Save(ctx, &model.Proxy{IP: "2.2.2.2", Port: "80", Type: model.ProxyTypeHTTP})
+
+	// 模拟超过有效期未更新
+	s.proxies["1.1.1.1:80"].expires = time.Now().Add(-time.Second)
+
+	if _, err := s.Get(ctx, "1.1.1.1:80"); err != ErrNotFound {
+		t.Errorf("Get() expired proxy error = %v, want ErrNotFound", err)
+	}
+	if err := s.UpdateStatus(ctx, &model.Proxy{IP: "1.1.1.1", Port: "80"}); err != ErrNotFound {
+		t.Errorf("UpdateStatus() expired proxy error = %v, want ErrNotFound", err)
+	}
+
+	all, _ := s.GetAll(ctx)
+	if !sameIPs(all, []string{"2.2.2.2"}) {
+		t.Errorf("GetAll() = %v, want [2.2.2.2]", ips(all))
+	}
+	if _, ok := s.proxies["1.1.1.1:80"]; ok {
+		t.Error("expired proxy not purged")
+	}
+
+	// 再次保存会刷新有效期
+	s.Save(ctx, &model.Proxy{IP: "1.1.1.1", Port: "80", Type: model.ProxyTypeHTTP})
+	if _, err := s.Get(ctx, "1.1.1.1:80"); err != nil {
+		t.Errorf("Get() saved again error = %v", err)
+	}
+}
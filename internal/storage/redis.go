@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"math/rand"
+	"strconv"
 	"time"
 
 	"github.com/langchou/proxyPool/internal/logger"
@@ -13,13 +14,41 @@ import (
 	"go.uber.org/zap"
 )
 
-type Storage interface {
-	Save(context.Context, *model.Proxy) error
-	GetAll(context.Context) ([]*model.Proxy, error)
-	GetRandom(context.Context) (*model.Proxy, error)
-	Remove(context.Context, string) error
-	UpdateScore(context.Context, string, int) error
-}
+// Redis 数据结构：
+//
+//	proxy:{ip}:{port}          每个代理一个 hash，字段为代理 JSON 的顶层字段
+//	proxies:all                所有代理的 set
+//	proxies:score              按评分排序的 zset
+//	proxies:speed              按响应速度排序的 zset
+//	proxies:expire             按过期时间排序的 zset，用于清理长时间未更新的代理
+//	proxies:type:{type}        按实际支持的类型划分的 set
+//	proxies:anonymity:{level}  按匿名级别划分的 set
+//	proxies:target:{name}      通过指定验证目标的代理 set
+//	proxies:country:{code}     按出口国家划分的 set
+//	proxies:source:{name}      按来源代理源划分的 set
+//	proxies:countries          出现过的出口国家代码 set
+//	proxies:sources            出现过的代理源名称 set
+//	source_counts:{name}:{hour} 代理源一个小时内的计数 hash，超过保留时间自动过期
+const (
+	proxyKeyPrefix     = "proxy:"
+	allKey             = "proxies:all"
+	scoreKey           = "proxies:score"
+	speedKey           = "proxies:speed"
+	expireKey          = "proxies:expire"
+	typeKeyPrefix      = "proxies:type:"
+	anonymityKeyPrefix = "proxies:anonymity:"
+	targetKeyPrefix    = "proxies:target:"
+	countryKeyPrefix   = "proxies:country:"
+	sourceKeyPrefix    = "proxies:source:"
+	countriesKey       = "proxies:countries"
+	sourcesKey         = "proxies:sources"
+	sourceCountsPrefix = "source_counts:"
+	cursorKeyPrefix    = "cursor:"
+	indexesField       = "_indexes" // 记录代理所在的索引 set，删除或更新时使用
+	queryChunkSize     = 100
+	randomPickTries    = 3
+	migrateScanCount   = 100
+)
 
 type RedisStorage struct {
 	client *redis.Client
@@ -38,108 +67,736 @@ func NewRedisStorage(addr, password string, db int) *RedisStorage {
 // 实现 Storage 接口的方法...
 
 func (s *RedisStorage) Save(ctx context.Context, proxy *model.Proxy) error {
-	key := "proxy:" + proxy.IP + ":" + proxy.Port
+	member := proxy.IP + ":" + proxy.Port
+	key := proxyKeyPrefix + member
 	logger.Log.Debug("Saving proxy to Redis", zap.String("key", key))
 
-	// 将代理对象序列化为 JSON
-	data, err := json.Marshal(proxy)
+	fields, err := encodeProxy(proxy)
 	if err != nil {
 		logger.Log.Error("Failed to marshal proxy", zap.Error(err))
 		return err
 	}
 
-	// 使用 SET 命令而不是 HSET
-	err = s.client.Set(ctx, key, data, 24*time.Hour).Err() // 设置 24 小时过期
+	indexes := indexKeys(proxy)
+	data, err := json.Marshal(indexes)
 	if err != nil {
+		return err
+	}
+	fields[indexesField] = string(data)
+
+	// 代理类型等字段可能变化，先从旧索引中移除
+	oldIndexes, err := s.getIndexes(ctx, key)
+	if err != nil {
+		logger.Log.Error("Failed to get proxy indexes", zap.String("key", key), zap.Error(err))
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	for _, index := range oldIndexes {
+		pipe.SRem(ctx, index, member)
+	}
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, fields)
+	for _, index := range indexes {
+		pipe.SAdd(ctx, index, member)
+	}
+	pipe.SAdd(ctx, allKey, member)
+	if proxy.Country != "" {
+		pipe.SAdd(ctx, countriesKey, proxy.Country)
+	}
+	if proxy.Source != "" {
+		pipe.SAdd(ctx, sourcesKey, proxy.Source)
+	}
+	pipe.ZAdd(ctx, scoreKey, redis.Z{Score: float64(proxy.Score), Member: member})
+	pipe.ZAdd(ctx, speedKey, redis.Z{Score: float64(proxy.Speed), Member: member})
+	pipe.ZAdd(ctx, expireKey, redis.Z{Score: float64(time.Now().Add(proxyTTL).Unix()), Member: member}) // 24 小时未更新则过期
+
+	if _, err := pipe.Exec(ctx); err != nil {
 		logger.Log.Error("Failed to save proxy", zap.String("key", key), zap.Error(err))
+		return err
 	}
-	return err
+	return nil
+}
+
+func (s *RedisStorage) Get(ctx context.Context, key string) (*model.Proxy, error) {
+	fields, err := s.client.HGetAll(ctx, proxyKeyPrefix+key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+
+	proxy, err := decodeProxy(fields)
+	if err != nil {
+		logger.Log.Error("Failed to unmarshal proxy", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+
+	return proxy, nil
 }
 
 func (s *RedisStorage) GetAll(ctx context.Context) ([]*model.Proxy, error) {
-	keys, err := s.client.Keys(ctx, "proxy:*").Result()
+	return s.Query(ctx, Filter{})
+}
+
+func (s *RedisStorage) GetRandom(ctx context.Context) (*model.Proxy, error) {
+	s.purgeExpired(ctx)
+
+	// 索引中可能残留已删除的代理，多尝试几次
+	for i := 0; i < randomPickTries; i++ {
+		member, err := s.client.SRandMember(ctx, allKey).Result()
+		if err == redis.Nil {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		proxy, err := s.Get(ctx, member)
+		if err == ErrNotFound {
+			s.Remove(ctx, member)
+			continue
+		}
+		if err != nil {
+			logger.Log.Error("Failed to get random proxy", zap.String("key", member), zap.Error(err))
+			return nil, err
+		}
+		return proxy, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// Query 按条件查询代理，类型、匿名级别、验证目标、出口国家和来源通过索引 set 过滤，排序通过 zset 完成，
+// 没有过滤条件的随机查询通过 SRANDMEMBER 只读取需要的数量
+func (s *RedisStorage) Query(ctx context.Context, filter Filter) ([]*model.Proxy, error) {
+	s.purgeExpired(ctx)
+
+	candidates, err := s.candidateMembers(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	proxies := make([]*model.Proxy, 0, len(keys))
-	for _, key := range keys {
-		// 使用 GET 命令获取 JSON 数据
-		data, err := s.client.Get(ctx, key).Result()
+	var members []string
+	switch {
+	case filter.OrderBy == OrderByRandom:
+		members, err = s.randomMembers(ctx, filter, candidates)
+		if err != nil {
+			return nil, err
+		}
+	case filter.OrderBy != "":
+		// 出口国家排除和 ASN 没有索引，需要读取代理后再过滤，此时不能在 zset 上提前截取
+		limit := filter.Limit
+		if !indexedFilter(filter) {
+			limit = 0
+		}
+		members, err = s.orderedMembers(ctx, filter.OrderBy, candidates, limit)
 		if err != nil {
-			if err != redis.Nil {
-				logger.Log.Error("Failed to get proxy", zap.String("key", key), zap.Error(err))
+			return nil, err
+		}
+	case candidates != nil:
+		members = make([]string, 0, len(candidates))
+		for member := range candidates {
+			members = append(members, member)
+		}
+	default:
+		members, err = s.client.SMembers(ctx, allKey).Result()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 分批流水线读取，直到满足数量要求。过滤条件都走索引时读取的代理基本都满足条件，每批只读取需要的数量
+	chunk := queryChunkSize
+	if filter.Limit > 0 && filter.Limit < chunk && indexedFilter(filter) {
+		chunk = filter.Limit
+	}
+	proxies := make([]*model.Proxy, 0, len(members))
+	for start := 0; start < len(members); start += chunk {
+		end := start + chunk
+		if end > len(members) {
+			end = len(members)
+		}
+
+		batch, err := s.getProxies(ctx, members[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, proxy := range batch {
+			if !filter.Match(proxy) {
+				continue
+			}
+			proxies = append(proxies, proxy)
+			if filter.Limit > 0 && len(proxies) >= filter.Limit {
+				return proxies, nil
 			}
+		}
+	}
+
+	return proxies, nil
+}
+
+func (s *RedisStorage) Remove(ctx context.Context, key string) error {
+	fullKey := proxyKeyPrefix + key
+
+	indexes, err := s.getIndexes(ctx, fullKey)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	for _, index := range indexes {
+		pipe.SRem(ctx, index, key)
+	}
+	pipe.SRem(ctx, allKey, key)
+	pipe.ZRem(ctx, scoreKey, key)
+	pipe.ZRem(ctx, speedKey, key)
+	pipe.ZRem(ctx, expireKey, key)
+	pipe.Del(ctx, fullKey)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStorage) UpdateStatus(ctx context.Context, proxy *model.Proxy) error {
+	key := proxy.IP + ":" + proxy.Port
+	fullKey := proxyKeyPrefix + key
+
+	history, err := json.Marshal(proxy.History)
+	if err != nil {
+		return err
+	}
+
+	exists, err := s.client.Exists(ctx, fullKey).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, fullKey, "score", strconv.Itoa(proxy.Score), "history", string(history))
+	pipe.ZAdd(ctx, scoreKey, redis.Z{Score: float64(proxy.Score), Member: key})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// IncrCursor 原子地增加指定游标并返回增加后的值，用于轮询选择代理
+func (s *RedisStorage) IncrCursor(ctx context.Context, name string, delta int64) (int64, error) {
+	return s.client.IncrBy(ctx, cursorKeyPrefix+name, delta).Result()
+}
+
+// Stats 通过索引计算代理池统计：数量取自各索引 set 的基数和评分 zset 的区间计数，速度分布只读取速度 zset 的分数
+func (s *RedisStorage) Stats(ctx context.Context) (*PoolStats, error) {
+	s.purgeExpired(ctx)
+
+	sources, err := s.client.SMembers(ctx, sourcesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	countries, err := s.client.SMembers(ctx, countriesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	totalCmd := pipe.SCard(ctx, allKey)
+	typeCmds := make([]*redis.IntCmd, len(statsTypes))
+	for i, t := range statsTypes {
+		typeCmds[i] = pipe.SCard(ctx, typeKeyPrefix+string(t))
+	}
+	anonymityCmds := make([]*redis.IntCmd, len(statsAnonymity))
+	for i, level := range statsAnonymity {
+		anonymityCmds[i] = pipe.SCard(ctx, anonymityKeyPrefix+string(level))
+	}
+	bandCmds := make([]*redis.IntCmd, len(scoreBandBounds)+1)
+	for i := range bandCmds {
+		from, to := "-inf", "+inf"
+		if i > 0 {
+			from = strconv.Itoa(scoreBandBounds[i-1])
+		}
+		if i < len(scoreBandBounds) {
+			to = "(" + strconv.Itoa(scoreBandBounds[i])
+		}
+		bandCmds[i] = pipe.ZCount(ctx, scoreKey, from, to)
+	}
+	countryCmds := make([]*redis.IntCmd, len(countries))
+	for i, country := range countries {
+		countryCmds[i] = pipe.SCard(ctx, countryKeyPrefix+country)
+	}
+	sourceCmds := make([]*redis.IntCmd, len(sources))
+	for i, source := range sources {
+		sourceCmds[i] = pipe.SCard(ctx, sourceKeyPrefix+source)
+	}
+	speedCmd := pipe.ZRangeByScoreWithScores(ctx, speedKey, &redis.ZRangeBy{Min: "(0", Max: "+inf"})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	stats := newPoolStats()
+	stats.Total = int(totalCmd.Val())
+	for i, t := range statsTypes {
+		stats.ByType[string(t)] = int(typeCmds[i].Val())
+	}
+	known := 0
+	for i, level := range statsAnonymity {
+		stats.ByAnonymity[string(level)] = int(anonymityCmds[i].Val())
+		known += int(anonymityCmds[i].Val())
+	}
+	stats.ByAnonymity[unknownLabel] = max(stats.Total-known, 0)
+	known = 0
+	for i, country := range countries {
+		if n := int(countryCmds[i].Val()); n > 0 {
+			stats.ByCountry[country] = n
+			known += n
+		}
+	}
+	if stats.Total > known {
+		stats.ByCountry[unknownLabel] = stats.Total - known
+	}
+	known = 0
+	for i, source := range sources {
+		if n := int(sourceCmds[i].Val()); n > 0 {
+			stats.BySource[source] = n
+			known += n
+		}
+	}
+	if stats.Total > known {
+		stats.BySource[unknownLabel] = stats.Total - known
+	}
+	for i, cmd := range bandCmds {
+		stats.ByScore[i].Count = int(cmd.Val())
+	}
+
+	speeds := make([]int64, len(speedCmd.Val()))
+	for i, z := range speedCmd.Val() {
+		speeds[i] = int64(z.Score)
+	}
+	stats.setSpeeds(speeds)
+	return stats, nil
+}
+
+// AddSourceCounts 累加代理源当前小时的计数，计数 hash 超过保留时间后自动过期
+func (s *RedisStorage) AddSourceCounts(ctx context.Context, name string, counts SourceCounts) error {
+	hour := sourceHour(time.Now())
+	key := sourceCountsKey(name, hour)
+
+	pipe := s.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, "fetched", counts.Fetched)
+	pipe.HIncrBy(ctx, key, "validated", counts.Validated)
+	pipe.HIncrBy(ctx, key, "valid", counts.Valid)
+	pipe.HIncrBy(ctx, key, "added", counts.Added)
+	pipe.ExpireAt(ctx, key, time.Unix(hour, 0).Add(sourceStatsRetention+time.Hour))
+	pipe.SAdd(ctx, sourcesKey, name)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// SourceStats 汇总代理源最近的计数和代理池中各代理源代理的存活情况，代理只读取 first_seen 字段
+func (s *RedisStorage) SourceStats(ctx context.Context) ([]SourceStats, error) {
+	s.purgeExpired(ctx)
+
+	sources, err := s.client.SMembers(ctx, sourcesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var hours []int64
+	for hour := sourceHourCutoff(now); hour <= sourceHour(now); hour += int64(time.Hour.Seconds()) {
+		hours = append(hours, hour)
+	}
+
+	pipe := s.client.Pipeline()
+	memberCmds := make([]*redis.StringSliceCmd, len(sources))
+	countCmds := make([][]*redis.MapStringStringCmd, len(sources))
+	for i, source := range sources {
+		memberCmds[i] = pipe.SMembers(ctx, sourceKeyPrefix+source)
+		countCmds[i] = make([]*redis.MapStringStringCmd, len(hours))
+		for j, hour := range hours {
+			countCmds[i][j] = pipe.HGetAll(ctx, sourceCountsKey(source, hour))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	builder := newSourceStatsBuilder(now)
+	for i, source := range sources {
+		for j, hour := range hours {
+			fields := countCmds[i][j].Val()
+			if len(fields) == 0 {
+				continue
+			}
+			builder.addCounts(source, hour, parseSourceCounts(fields))
+		}
+	}
+
+	// 流水线读取各代理的首次发现时间
+	pipe = s.client.Pipeline()
+	firstSeenCmds := make([][]*redis.StringCmd, len(sources))
+	for i, cmd := range memberCmds {
+		firstSeenCmds[i] = make([]*redis.StringCmd, len(cmd.Val()))
+		for j, member := range cmd.Val() {
+			firstSeenCmds[i][j] = pipe.HGet(ctx, proxyKeyPrefix+member, "first_seen")
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, source := range sources {
+		for _, cmd := range firstSeenCmds[i] {
+			if cmd.Err() == redis.Nil {
+				continue
+			}
+			var firstSeen time.Time
+			json.Unmarshal([]byte(cmd.Val()), &firstSeen)
+			builder.addProxy(source, firstSeen)
+		}
+	}
+	return builder.result(), nil
+}
+
+// Migrate 将旧版本以 JSON 字符串保存的 proxy:* 键转换为新的 hash 结构，返回迁移数量
+func (s *RedisStorage) Migrate(ctx context.Context) (int, error) {
+	migrated := 0
+
+	iter := s.client.Scan(ctx, 0, proxyKeyPrefix+"*", migrateScanCount).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		keyType, err := s.client.Type(ctx, key).Result()
+		if err != nil {
+			return migrated, err
+		}
+		if keyType != "string" {
 			continue
 		}
 
+		data, err := s.client.Get(ctx, key).Result()
+		if err != nil {
+			if err == redis.Nil {
+				continue
+			}
+			return migrated, err
+		}
+
 		var proxy model.Proxy
 		if err := json.Unmarshal([]byte(data), &proxy); err != nil {
-			logger.Log.Error("Failed to unmarshal proxy", zap.String("key", key), zap.Error(err))
+			logger.Log.Warn("Skipping invalid legacy proxy", zap.String("key", key), zap.Error(err))
 			continue
 		}
 
-		proxies = append(proxies, &proxy)
+		// Save 会删除旧的字符串键并写入 hash 和索引
+		if err := s.Save(ctx, &proxy); err != nil {
+			return migrated, err
+		}
+		migrated++
 	}
 
-	return proxies, nil
+	if err := iter.Err(); err != nil {
+		return migrated, err
+	}
+	return migrated, nil
 }
 
-func (s *RedisStorage) GetRandom(ctx context.Context) (*model.Proxy, error) {
-	keys, err := s.client.Keys(ctx, "proxy:*").Result()
+// 在 RedisStorage 结构体中添加获取客户端的方法
+func (s *RedisStorage) GetRedisClient() *redis.Client {
+	return s.client
+}
+
+// candidateMembers 根据索引 set 计算满足类型、匿名级别、验证目标、出口国家和来源条件的代理，返回 nil 表示不限制
+func (s *RedisStorage) candidateMembers(ctx context.Context, filter Filter) (map[string]bool, error) {
+	if len(filter.Types) == 0 && len(filter.Anonymity) == 0 && filter.Target == "" && len(filter.Countries) == 0 && len(filter.Sources) == 0 {
+		return nil, nil
+	}
+
+	pipe := s.client.Pipeline()
+	var typeCmd, anonymityCmd, targetCmd, countryCmd, sourceCmd *redis.StringSliceCmd
+	if len(filter.Types) > 0 {
+		keys := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			keys[i] = typeKeyPrefix + string(t)
+		}
+		typeCmd = pipe.SUnion(ctx, keys...)
+	}
+	if len(filter.Anonymity) > 0 {
+		keys := make([]string, len(filter.Anonymity))
+		for i, level := range filter.Anonymity {
+			keys[i] = anonymityKeyPrefix + string(level)
+		}
+		anonymityCmd = pipe.SUnion(ctx, keys...)
+	}
+	if filter.Target != "" {
+		targetCmd = pipe.SMembers(ctx, targetKeyPrefix+filter.Target)
+	}
+	if len(filter.Countries) > 0 {
+		keys := make([]string, len(filter.Countries))
+		for i, country := range filter.Countries {
+			keys[i] = countryKeyPrefix + country
+		}
+		countryCmd = pipe.SUnion(ctx, keys...)
+	}
+	if len(filter.Sources) > 0 {
+		keys := make([]string, len(filter.Sources))
+		for i, source := range filter.Sources {
+			keys[i] = sourceKeyPrefix + source
+		}
+		sourceCmd = pipe.SUnion(ctx, keys...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var candidates map[string]bool
+	for _, cmd := range []*redis.StringSliceCmd{typeCmd, anonymityCmd, targetCmd, countryCmd, sourceCmd} {
+		if cmd == nil {
+			continue
+		}
+		set := make(map[string]bool, len(cmd.Val()))
+		for _, member := range cmd.Val() {
+			if candidates == nil || candidates[member] {
+				set[member] = true
+			}
+		}
+		candidates = set
+	}
+	return candidates, nil
+}
+
+// indexedFilter 过滤条件是否都可以通过索引 set 完成
+func indexedFilter(filter Filter) bool {
+	return len(filter.Excluded) == 0 && len(filter.ASNs) == 0
+}
+
+// randomMembers 随机顺序返回代理，没有过滤条件时通过 SRANDMEMBER 只取需要的数量，否则打乱候选代理的顺序
+func (s *RedisStorage) randomMembers(ctx context.Context, filter Filter, candidates map[string]bool) ([]string, error) {
+	if candidates == nil && filter.Limit > 0 && indexedFilter(filter) {
+		// 索引中可能残留已删除的代理，多取几个
+		return s.client.SRandMemberN(ctx, allKey, int64(filter.Limit+randomPickTries)).Result()
+	}
+
+	var members []string
+	if candidates != nil {
+		members = make([]string, 0, len(candidates))
+		for member := range candidates {
+			members = append(members, member)
+		}
+	} else {
+		var err error
+		members, err = s.client.SMembers(ctx, allKey).Result()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	return members, nil
+}
+
+// orderedMembers 按排序 zset 返回代理，candidates 不为 nil 时只保留其中的代理，limit 大于 0 时在 zset 上直接截取
+func (s *RedisStorage) orderedMembers(ctx context.Context, orderBy string, candidates map[string]bool, limit int) ([]string, error) {
+	// 没有其他过滤条件时直接在 zset 上截取
+	stop := int64(-1)
+	if candidates == nil && limit > 0 {
+		stop = int64(limit) - 1
+	}
+
+	var members []string
+	var err error
+	switch orderBy {
+	case OrderBySpeed:
+		members, err = s.client.ZRange(ctx, speedKey, 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+		members, err = s.speedOrder(ctx, members)
+		if err != nil {
+			return nil, err
+		}
+		if stop >= 0 && int64(len(members)) > stop+1 {
+			members = members[:stop+1]
+		}
+	default:
+		members, err = s.client.ZRevRange(ctx, scoreKey, 0, stop).Result()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if candidates == nil {
+		return members, nil
+	}
+
+	filtered := make([]string, 0, len(candidates))
+	for _, member := range members {
+		if candidates[member] {
+			filtered = append(filtered, member)
+		}
+	}
+	return filtered, nil
+}
+
+// speedOrder 将未测速（速度为 0）的代理移到最后
+func (s *RedisStorage) speedOrder(ctx context.Context, members []string) ([]string, error) {
+	unknown, err := s.client.ZCount(ctx, speedKey, "-inf", "0").Result()
 	if err != nil {
 		return nil, err
 	}
+	if unknown == 0 || int(unknown) > len(members) {
+		return members, nil
+	}
+	return append(members[unknown:], members[:unknown]...), nil
+}
 
-	if len(keys) == 0 {
-		return nil, redis.Nil
+// getProxies 流水线读取多个代理，顺带清理索引中残留的已删除代理
+func (s *RedisStorage) getProxies(ctx context.Context, members []string) ([]*model.Proxy, error) {
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(members))
+	for i, member := range members {
+		cmds[i] = pipe.HGetAll(ctx, proxyKeyPrefix+member)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
 	}
 
-	// 随机选择一个代理
-	key := keys[rand.Intn(len(keys))]
+	proxies := make([]*model.Proxy, 0, len(members))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			s.Remove(ctx, members[i])
+			continue
+		}
 
-	// 使用 GET 命令获取 JSON 数据
-	data, err := s.client.Get(ctx, key).Result()
+		proxy, err := decodeProxy(fields)
+		if err != nil {
+			logger.Log.Error("Failed to unmarshal proxy", zap.String("key", members[i]), zap.Error(err))
+			continue
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// getIndexes 读取代理当前所在的索引 set
+func (s *RedisStorage) getIndexes(ctx context.Context, key string) ([]string, error) {
+	data, err := s.client.HGet(ctx, key, indexesField).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
 	if err != nil {
-		logger.Log.Error("Failed to get random proxy", zap.String("key", key), zap.Error(err))
+		// 旧版本的字符串键没有索引字段
+		if keyType, _ := s.client.Type(ctx, key).Result(); keyType == "string" {
+			return nil, nil
+		}
 		return nil, err
 	}
 
-	var proxy model.Proxy
-	if err := json.Unmarshal([]byte(data), &proxy); err != nil {
-		logger.Log.Error("Failed to unmarshal proxy", zap.String("key", key), zap.Error(err))
+	var indexes []string
+	if err := json.Unmarshal([]byte(data), &indexes); err != nil {
 		return nil, err
 	}
+	return indexes, nil
+}
 
-	return &proxy, nil
+// purgeExpired 清理超过有效期未更新的代理
+func (s *RedisStorage) purgeExpired(ctx context.Context) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	members, err := s.client.ZRangeByScore(ctx, expireKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		logger.Log.Error("Failed to get expired proxies", zap.Error(err))
+		return
+	}
+
+	for _, member := range members {
+		if err := s.Remove(ctx, member); err != nil {
+			logger.Log.Error("Failed to remove expired proxy", zap.String("key", member), zap.Error(err))
+		}
+	}
 }
 
-func (s *RedisStorage) Remove(ctx context.Context, key string) error {
-	return s.client.Del(ctx, "proxy:"+key).Err()
+// indexKeys 返回代理应当加入的索引 set
+func indexKeys(proxy *model.Proxy) []string {
+	var indexes []string
+	for _, t := range proxy.SupportedTypes() {
+		indexes = append(indexes, typeKeyPrefix+string(t))
+	}
+	if proxy.Anonymity.IsValid() {
+		indexes = append(indexes, anonymityKeyPrefix+string(proxy.Anonymity))
+	}
+	for _, target := range proxy.Targets {
+		indexes = append(indexes, targetKeyPrefix+target)
+	}
+	if proxy.Country != "" {
+		indexes = append(indexes, countryKeyPrefix+proxy.Country)
+	}
+	if proxy.Source != "" {
+		indexes = append(indexes, sourceKeyPrefix+proxy.Source)
+	}
+	return indexes
 }
 
-func (s *RedisStorage) UpdateScore(ctx context.Context, key string, score int) error {
-	fullKey := "proxy:" + key
+// sourceCountsKey 代理源一个小时计数的 key
+func sourceCountsKey(name string, hour int64) string {
+	return sourceCountsPrefix + name + ":" + strconv.FormatInt(hour, 10)
+}
 
-	// 先获取现有数据
-	data, err := s.client.Get(ctx, fullKey).Result()
-	if err != nil {
-		return err
+// parseSourceCounts 解析计数 hash
+func parseSourceCounts(fields map[string]string) SourceCounts {
+	parse := func(name string) int64 {
+		n, _ := strconv.ParseInt(fields[name], 10, 64)
+		return n
+	}
+	return SourceCounts{
+		Fetched:   parse("fetched"),
+		Validated: parse("validated"),
+		Valid:     parse("valid"),
+		Added:     parse("added"),
 	}
+}
 
-	var proxy model.Proxy
-	if err := json.Unmarshal([]byte(data), &proxy); err != nil {
-		return err
+// encodeProxy 将代理转换为 hash 字段，每个字段保存对应 JSON 值
+func encodeProxy(proxy *model.Proxy) (map[string]interface{}, error) {
+	data, err := json.Marshal(proxy)
+	if err != nil {
+		return nil, err
 	}
 
-	// 更新分数
-	proxy.Score = score
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
 
-	// 重新保存
-	return s.Save(ctx, &proxy)
+	fields := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		fields[k] = string(v)
+	}
+	return fields, nil
 }
 
-// 在 RedisStorage 结构体中添加获取客户端的方法
-func (s *RedisStorage) GetRedisClient() *redis.Client {
-	return s.client
+// decodeProxy 将 hash 字段还原为代理
+func decodeProxy(fields map[string]string) (*model.Proxy, error) {
+	raw := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		if k == indexesField {
+			continue
+		}
+		raw[k] = json.RawMessage(v)
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var proxy model.Proxy
+	if err := json.Unmarshal(data, &proxy); err != nil {
+		return nil, err
+	}
+	return &proxy, nil
 }
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// 代理源统计的时间范围
+const (
+	sourceStatsWindow    = 24 * time.Hour // 抓取、验证数量和存活率的统计窗口
+	sourceStatsRetention = 48 * time.Hour // 小时计数的保留时间，需要覆盖 24 小时存活率的统计窗口
+)
+
+// SourceCounts 代理源的抓取和验证计数
+type SourceCounts struct {
+	Fetched   int64 `json:"fetched"`   // 抓取到的代理数
+	Validated int64 `json:"validated"` // 验证的代理数
+	Valid     int64 `json:"valid"`     // 验证通过的代理数
+	Added     int64 `json:"added"`     // 首次加入代理池的代理数
+}
+
+// SourceStats 代理源最近 24 小时的质量统计
+type SourceStats struct {
+	Name         string `json:"name"`
+	Proxies      int    `json:"proxies"` // 代理池中来自该代理源的代理数
+	SourceCounts        // 最近 24 小时的累计计数
+
+	// 在 24 小时窗口内加入代理池、且已加入超过 1 小时（24 小时）的代理中，仍在代理池中的百分比，没有数据时为 null
+	Survival1h  *float64 `json:"survival_1h"`
+	Survival24h *float64 `json:"survival_24h"`
+}
+
+func (c *SourceCounts) add(other SourceCounts) {
+	c.Fetched += other.Fetched
+	c.Validated += other.Validated
+	c.Valid += other.Valid
+	c.Added += other.Added
+}
+
+// sourceHour 计数所在的小时，取整到小时的 Unix 时间戳
+func sourceHour(t time.Time) int64 {
+	return t.Truncate(time.Hour).Unix()
+}
+
+// sourceHourCutoff 早于该小时的计数不再保留
+func sourceHourCutoff(now time.Time) int64 {
+	return sourceHour(now) - int64(sourceStatsRetention.Seconds())
+}
+
+// sourceSurvival 一个存活率统计的加入数量和仍存活的数量
+type sourceSurvival struct {
+	added int64
+	alive int64
+}
+
+// sourceStatsBuilder 由各存储后端读取的小时计数和代理池中代理的加入时间计算代理源统计
+type sourceStatsBuilder struct {
+	current  int64 // 当前小时
+	stats    map[string]*SourceStats
+	after1h  map[string]*sourceSurvival // 加入超过 1 小时的代理
+	after24h map[string]*sourceSurvival // 加入超过 24 小时的代理
+}
+
+func newSourceStatsBuilder(now time.Time) *sourceStatsBuilder {
+	return &sourceStatsBuilder{
+		current:  sourceHour(now),
+		stats:    make(map[string]*SourceStats),
+		after1h:  make(map[string]*sourceSurvival),
+		after24h: make(map[string]*sourceSurvival),
+	}
+}
+
+// addCounts 计入代理源一个小时的计数
+func (b *sourceStatsBuilder) addCounts(name string, hour int64, counts SourceCounts) {
+	stats := b.source(name)
+	if b.inWindow(hour, 0) {
+		stats.SourceCounts.add(counts)
+	}
+	if b.inWindow(hour, time.Hour) {
+		b.survival(b.after1h, name).added += counts.Added
+	}
+	if b.inWindow(hour, sourceStatsWindow) {
+		b.survival(b.after24h, name).added += counts.Added
+	}
+}
+
+// addProxy 计入代理池中的一个代理
+func (b *sourceStatsBuilder) addProxy(name string, firstSeen time.Time) {
+	b.source(name).Proxies++
+	if firstSeen.IsZero() {
+		return
+	}
+	hour := sourceHour(firstSeen)
+	if b.inWindow(hour, time.Hour) {
+		b.survival(b.after1h, name).alive++
+	}
+	if b.inWindow(hour, sourceStatsWindow) {
+		b.survival(b.after24h, name).alive++
+	}
+}
+
+// result 返回按名称排序的代理源统计
+func (b *sourceStatsBuilder) result() []SourceStats {
+	result := make([]SourceStats, 0, len(b.stats))
+	for name, stats := range b.stats {
+		stats.Survival1h = b.after1h[name].rate()
+		stats.Survival24h = b.after24h[name].rate()
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// inWindow 小时是否在 age 之前的 24 小时窗口内，且该小时结束时距今已超过 age
+func (b *sourceStatsBuilder) inWindow(hour int64, age time.Duration) bool {
+	if age == 0 {
+		return hour > b.current-int64(sourceStatsWindow.Seconds()) && hour <= b.current
+	}
+	end := b.current - int64(age.Seconds())
+	return hour >= end-int64(sourceStatsWindow.Seconds()) && hour < end
+}
+
+func (b *sourceStatsBuilder) source(name string) *SourceStats {
+	stats, ok := b.stats[name]
+	if !ok {
+		stats = &SourceStats{Name: name}
+		b.stats[name] = stats
+	}
+	return stats
+}
+
+func (b *sourceStatsBuilder) survival(m map[string]*sourceSurvival, name string) *sourceSurvival {
+	s, ok := m[name]
+	if !ok {
+		s = &sourceSurvival{}
+		m[name] = s
+	}
+	return s
+}
+
+// rate 存活百分比，没有加入记录时返回 nil
+func (s *sourceSurvival) rate() *float64 {
+	if s == nil || s.added == 0 {
+		return nil
+	}
+	rate := float64(s.alive) * 100 / float64(s.added)
+	if rate > 100 {
+		// 计数在爬取结束时才保存，爬取中途退出时存活数可能超过加入数
+		rate = 100
+	}
+	return &rate
+}
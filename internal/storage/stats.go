@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// unknownLabel 未检测匿名级别、没有出口国家或来源记录的代理在统计中使用的名称
+const unknownLabel = "unknown"
+
+// scoreBandBounds 评分区间的分界线，区间为 <20、20-39、40-59、60-79、>=80
+var scoreBandBounds = []int{20, 40, 60, 80}
+
+// statsTypes 按类型统计时使用的代理类型
+var statsTypes = []model.ProxyType{
+	model.ProxyTypeHTTP,
+	model.ProxyTypeHTTPS,
+	model.ProxyTypeSOCKS4,
+	model.ProxyTypeSOCKS5,
+}
+
+// statsAnonymity 按匿名级别统计时使用的级别，未检测的代理单独计入 unknown
+var statsAnonymity = []model.AnonymityLevel{
+	model.AnonymityTransparent,
+	model.AnonymityAnonymous,
+	model.AnonymityElite,
+}
+
+// PoolStats 代理池的统计信息，由存储后端直接计算，不需要读取并返回全部代理
+type PoolStats struct {
+	Total       int            `json:"total"`        // 代理总数
+	ByType      map[string]int `json:"by_type"`      // 按实际支持的类型统计，支持多种类型的代理在每种类型中各计一次
+	ByAnonymity map[string]int `json:"by_anonymity"` // 按匿名级别统计，未检测的代理计入 unknown
+	ByCountry   map[string]int `json:"by_country"`   // 按出口国家统计，没有地理位置信息的代理计入 unknown
+	BySource    map[string]int `json:"by_source"`    // 按来源代理源统计，没有来源记录的代理计入 unknown
+	ByScore     []ScoreBand    `json:"by_score"`     // 按评分区间统计，从低到高
+	Speed       SpeedStats     `json:"speed"`        // 已测速代理的响应速度
+}
+
+// ScoreBand 一个评分区间内的代理数量
+type ScoreBand struct {
+	Band  string `json:"band"`  // 区间，例如 20-39
+	Count int    `json:"count"` // 代理数量
+}
+
+// SpeedStats 已测速代理的响应速度统计（毫秒）
+type SpeedStats struct {
+	Measured int   `json:"measured"` // 已测速的代理数
+	Average  int64 `json:"avg_ms"`   // 平均响应速度
+	P50      int64 `json:"p50_ms"`
+	P90      int64 `json:"p90_ms"`
+	P95      int64 `json:"p95_ms"`
+	P99      int64 `json:"p99_ms"`
+}
+
+// newPoolStats 创建各分类计数为 0 的统计
+func newPoolStats() *PoolStats {
+	stats := &PoolStats{
+		ByType:      make(map[string]int, len(statsTypes)),
+		ByAnonymity: make(map[string]int),
+		ByCountry:   make(map[string]int),
+		BySource:    make(map[string]int),
+		ByScore:     make([]ScoreBand, len(scoreBandBounds)+1),
+	}
+	for _, t := range statsTypes {
+		stats.ByType[string(t)] = 0
+	}
+	for _, level := range statsAnonymity {
+		stats.ByAnonymity[string(level)] = 0
+	}
+	stats.ByAnonymity[unknownLabel] = 0
+
+	for i := range stats.ByScore {
+		switch {
+		case i == 0:
+			stats.ByScore[i].Band = fmt.Sprintf("<%d", scoreBandBounds[0])
+		case i == len(scoreBandBounds):
+			stats.ByScore[i].Band = fmt.Sprintf(">=%d", scoreBandBounds[i-1])
+		default:
+			stats.ByScore[i].Band = fmt.Sprintf("%d-%d", scoreBandBounds[i-1], scoreBandBounds[i]-1)
+		}
+	}
+	return stats
+}
+
+// add 将单个代理计入统计，速度需要另外通过 setSpeeds 计算
+func (s *PoolStats) add(p *model.Proxy) {
+	s.Total++
+	for _, t := range p.SupportedTypes() {
+		s.ByType[string(t)]++
+	}
+	if p.Anonymity.IsValid() {
+		s.ByAnonymity[string(p.Anonymity)]++
+	} else {
+		s.ByAnonymity[unknownLabel]++
+	}
+	s.ByCountry[labelOrUnknown(p.Country)]++
+	s.BySource[labelOrUnknown(p.Source)]++
+	s.ByScore[scoreBand(p.Score)].Count++
+}
+
+// setSpeeds 根据已测速代理的响应速度计算平均值和百分位数，speeds 中的 0 值会被忽略
+func (s *PoolStats) setSpeeds(speeds []int64) {
+	measured := make([]int64, 0, len(speeds))
+	var sum int64
+	for _, speed := range speeds {
+		if speed > 0 {
+			measured = append(measured, speed)
+			sum += speed
+		}
+	}
+	if len(measured) == 0 {
+		s.Speed = SpeedStats{}
+		return
+	}
+
+	sort.Slice(measured, func(i, j int) bool { return measured[i] < measured[j] })
+	s.Speed = SpeedStats{
+		Measured: len(measured),
+		Average:  sum / int64(len(measured)),
+		P50:      model.Percentile(measured, 50),
+		P90:      model.Percentile(measured, 90),
+		P95:      model.Percentile(measured, 95),
+		P99:      model.Percentile(measured, 99),
+	}
+}
+
+// labelOrUnknown 国家或代理源在统计中使用的名称，为空时使用 unknown
+func labelOrUnknown(value string) string {
+	if value == "" {
+		return unknownLabel
+	}
+	return value
+}
+
+// scoreBand 返回评分所在区间的序号
+func scoreBand(score int) int {
+	for i, bound := range scoreBandBounds {
+		if score < bound {
+			return i
+		}
+	}
+	return len(scoreBandBounds)
+}
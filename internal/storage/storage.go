@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// ErrNotFound 代理不存在或代理池为空
+var ErrNotFound = errors.New("proxy not found")
+
+// proxyTTL 代理超过该时间未更新则过期
+const proxyTTL = 24 * time.Hour
+
+type Storage interface {
+	Save(context.Context, *model.Proxy) error
+	Get(context.Context, string) (*model.Proxy, error)
+	GetAll(context.Context) ([]*model.Proxy, error)
+	GetRandom(context.Context) (*model.Proxy, error)
+	Query(context.Context, Filter) ([]*model.Proxy, error)
+	Remove(context.Context, string) error
+	UpdateStatus(context.Context, *model.Proxy) error // 只更新评分和验证历史，不刷新过期时间
+	IncrCursor(context.Context, string, int64) (int64, error)
+	Stats(context.Context) (*PoolStats, error)                   // 代理池统计，尽量通过索引计算
+	AddSourceCounts(context.Context, string, SourceCounts) error // 累加代理源当前小时的计数
+	SourceStats(context.Context) ([]SourceStats, error)          // 代理源最近 24 小时的计数和代理存活率
+}
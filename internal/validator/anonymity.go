@@ -0,0 +1,143 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultJudgeURL  = "http://httpbin.org/get"
+	localIPTTL       = 30 * time.Minute // 本机出口 IP 的缓存时间
+	localIPRetryWait = time.Minute      // 获取本机出口 IP 失败后的重试间隔
+)
+
+// proxyHeaders 代理转发时可能添加的请求头，出现任意一个说明目标能察觉使用了代理
+var proxyHeaders = []string{"Via", "X-Forwarded-For", "Forwarded", "Proxy-Connection"}
+
+// judgeResponse 判定接口的响应，与 httpbin 的 /get 格式兼容
+type judgeResponse struct {
+	Origin  string            `json:"origin"`  // 判定接口看到的来源 IP
+	Headers map[string]string `json:"headers"` // 判定接口收到的请求头
+}
+
+// detectAnonymity 通过代理请求判定接口，根据来源 IP 和请求头判断匿名级别，同时返回判定接口看到的出口 IP
+// 和请求是否成功，请求失败时返回未知级别和空的出口 IP
+func (v *Validator) detectAnonymity(client *http.Client, p *model.Proxy) (model.AnonymityLevel, string, bool) {
+	judge, err := fetchJudge(client, v.judgeURL)
+	if err != nil {
+		logger.Log.Debug("Anonymity detection failed",
+			zap.String("ip", p.IP),
+			zap.String("port", p.Port),
+			zap.Error(err))
+		return model.AnonymityUnknown, "", false
+	}
+
+	// 透明代理的 origin 可能包含多个地址，最后一个才是连接判定接口的出口地址
+	var exitIP string
+	if addrs := splitAddrs(judge.Origin); len(addrs) > 0 {
+		exitIP = addrs[len(addrs)-1]
+	}
+	return classifyAnonymity(judge, v.localIP()), exitIP, true
+}
+
+// localIP 直连判定接口获取本机出口 IP，结果会缓存一段时间
+func (v *Validator) localIP() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.realIP != "" && time.Since(v.realIPAt) < localIPTTL {
+		return v.realIP
+	}
+	if v.realIP == "" && time.Since(v.realIPAt) < localIPRetryWait {
+		return ""
+	}
+
+	v.realIPAt = time.Now()
+	judge, err := fetchJudge(&http.Client{Timeout: v.timeout}, v.judgeURL)
+	if err != nil {
+		logger.Log.Warn("Failed to get local IP from judge, transparent proxies may be misclassified",
+			zap.String("judge", v.judgeURL),
+			zap.Error(err))
+		return v.realIP
+	}
+
+	ips := splitAddrs(judge.Origin)
+	if len(ips) == 0 {
+		logger.Log.Warn("Judge returned empty origin", zap.String("judge", v.judgeURL))
+		return v.realIP
+	}
+	v.realIP = ips[0]
+	logger.Log.Debug("Local IP detected", zap.String("ip", v.realIP))
+	return v.realIP
+}
+
+func fetchJudge(client *http.Client, judgeURL string) (*judgeResponse, error) {
+	resp, err := client.Get(judgeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	var judge judgeResponse
+	if err := json.Unmarshal(body, &judge); err != nil {
+		return nil, fmt.Errorf("invalid judge response: %w", err)
+	}
+	return &judge, nil
+}
+
+// classifyAnonymity 判断匿名级别：
+// 真实 IP 出现在来源或请求头中为透明代理，出现代理特征请求头为普通匿名，否则为高匿
+func classifyAnonymity(judge *judgeResponse, realIP string) model.AnonymityLevel {
+	if realIP != "" {
+		if containsAddr(judge.Origin, realIP) {
+			return model.AnonymityTransparent
+		}
+		for _, value := range judge.Headers {
+			if containsAddr(value, realIP) {
+				return model.AnonymityTransparent
+			}
+		}
+	}
+
+	for name := range judge.Headers {
+		for _, header := range proxyHeaders {
+			if strings.EqualFold(name, header) {
+				return model.AnonymityAnonymous
+			}
+		}
+	}
+	return model.AnonymityElite
+}
+
+// splitAddrs 拆分请求头中的地址列表，例如 "1.2.3.4, 5.6.7.8" 或 for="1.2.3.4"
+func splitAddrs(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return strings.ContainsRune(`,;= "`, r)
+	})
+}
+
+func containsAddr(value, ip string) bool {
+	for _, addr := range splitAddrs(value) {
+		if addr == ip {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package validator
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+
+	"github.com/langchou/proxyPool/internal/dialer"
+	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/model"
+	"go.uber.org/zap"
+)
+
+// detectOrder 协议探测结果的排列顺序，也是选择代理主类型的优先级
+var detectOrder = []model.ProxyType{
+	model.ProxyTypeHTTP,
+	model.ProxyTypeHTTPS,
+	model.ProxyTypeSOCKS5,
+	model.ProxyTypeSOCKS4,
+}
+
+// Detect 对类型未知的代理分别尝试 HTTP 转发、CONNECT、SOCKS4 和 SOCKS5 握手，返回代理支持的全部协议
+// HTTP 转发通过请求判定接口确认，其余协议以握手成功并连上探测地址为准
+func (v *Validator) Detect(p *model.Proxy) []model.ProxyType {
+	judgeAddr := urlAddr(v.judgeURL)
+	connectAddr := urlAddr(v.connectURL)
+
+	probes := map[model.ProxyType]func() bool{
+		model.ProxyTypeHTTP: func() bool {
+			client, err := v.createHTTPClient(p)
+			if err != nil {
+				return false
+			}
+			_, err = fetchJudge(client, v.judgeURL)
+			return err == nil
+		},
+		// 很多代理只允许 CONNECT 到 443 端口，使用 CONNECT 探测地址
+		model.ProxyTypeHTTPS:  v.handshake(p, model.ProxyTypeHTTPS, connectAddr),
+		model.ProxyTypeSOCKS4: v.handshake(p, model.ProxyTypeSOCKS4, judgeAddr),
+		model.ProxyTypeSOCKS5: v.handshake(p, model.ProxyTypeSOCKS5, judgeAddr),
+	}
+
+	// 各协议使用独立连接并发探测
+	supported := make(map[model.ProxyType]bool, len(probes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for t, probe := range probes {
+		wg.Add(1)
+		go func(t model.ProxyType, probe func() bool) {
+			defer wg.Done()
+			if probe() {
+				mu.Lock()
+				supported[t] = true
+				mu.Unlock()
+			}
+		}(t, probe)
+	}
+	wg.Wait()
+
+	var protocols []model.ProxyType
+	for _, t := range detectOrder {
+		if supported[t] {
+			protocols = append(protocols, t)
+		}
+	}
+
+	logger.Log.Debug("Detected proxy protocols",
+		zap.String("ip", p.IP),
+		zap.String("port", p.Port),
+		zap.Any("protocols", protocols))
+	return protocols
+}
+
+// PrimaryType 从探测到的协议中选择代理的主类型，HTTP 类协议优先，验证时会进一步区分普通转发和 CONNECT
+func PrimaryType(protocols []model.ProxyType) model.ProxyType {
+	for _, t := range detectOrder {
+		for _, protocol := range protocols {
+			if protocol == t {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// handshake 返回以指定协议通过代理连接探测地址的探测函数
+func (v *Validator) handshake(p *model.Proxy, t model.ProxyType, addr string) func() bool {
+	return func() bool {
+		candidate := *p
+		candidate.Type = t
+
+		ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+		defer cancel()
+
+		conn, err := dialer.Dial(ctx, &candidate, addr, v.timeout)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+}
+
+// urlAddr 返回 URL 对应的 host:port，未指定端口时按协议补全默认端口
+func urlAddr(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
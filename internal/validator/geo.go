@@ -0,0 +1,31 @@
+package validator
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// parseIPInfo 解析 ipinfo 风格的响应，返回出口 IP 和地理位置，不是这种格式时返回 false
+func parseIPInfo(body []byte) (string, model.GeoInfo, bool) {
+	var info IPInfo
+	if err := json.Unmarshal(body, &info); err != nil || info.IP == "" || info.Country == "" {
+		return "", model.GeoInfo{}, false
+	}
+
+	geo := model.GeoInfo{
+		Country: model.NormalizeCountry(info.Country),
+		Region:  info.Region,
+		City:    info.City,
+		Org:     info.Org,
+	}
+	// org 字段形如 "AS15169 Google LLC"
+	if asn, org, ok := strings.Cut(info.Org, " "); ok {
+		if n := model.ParseASN(asn); n != 0 {
+			geo.ASN = n
+			geo.Org = org
+		}
+	}
+	return info.IP, geo, true
+}
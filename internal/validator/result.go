@@ -0,0 +1,101 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/langchou/proxyPool/internal/model"
+)
+
+// 验证失败原因分类
+const (
+	ErrTimeout     = "timeout"      // 连接或读取超时
+	ErrRefused     = "refused"      // 连接被拒绝
+	ErrReset       = "reset"        // 连接被重置或提前关闭
+	ErrDNS         = "dns"          // 域名解析失败
+	ErrProxyAuth   = "proxy_auth"   // 代理要求认证
+	ErrBadStatus   = "bad_status"   // 目标返回非 200 状态码
+	ErrBadResponse = "bad_response" // 响应内容不符合预期
+	ErrUnsupported = "unsupported"  // 代理类型不支持
+	ErrOther       = "other"        // 其他错误
+)
+
+// Result 单次验证的结果
+type Result struct {
+	Valid   bool     // 是否至少通过一个验证目标
+	Speed   int64    // 第一个通过的目标的响应速度（毫秒），未通过时为 0
+	Error   string   // 失败原因分类，验证通过时为空
+	Targets []string // 通过的验证目标名称
+
+	Anonymity model.AnonymityLevel // 检测到的匿名级别，检测失败时为未知
+	ExitIP    string               // 判定接口看到的出口 IP，检测失败时为空
+	Geo       model.GeoInfo        // 验证目标返回的出口 IP 地理位置，目标不提供时为空
+
+	SupportsHTTP    bool // HTTP 代理是否支持普通转发
+	SupportsConnect bool // HTTP 代理是否支持 CONNECT 隧道
+}
+
+// Record 将验证结果转换为代理的验证记录
+func (r Result) Record() model.CheckRecord {
+	return model.CheckRecord{
+		Time:    time.Now(),
+		Success: r.Valid,
+		Latency: r.Speed,
+		Error:   r.Error,
+	}
+}
+
+// Apply 将验证通过的结果写入代理，未检测出的匿名级别和出口 IP 保留原值
+func (r Result) Apply(p *model.Proxy) {
+	p.Speed = r.Speed
+	p.Targets = r.Targets
+	p.SupportsHTTP = r.SupportsHTTP
+	p.SupportsConnect = r.SupportsConnect
+	if r.Anonymity != model.AnonymityUnknown {
+		p.Anonymity = r.Anonymity
+	}
+	if r.ExitIP != "" {
+		p.ExitIP = r.ExitIP
+	}
+	if !r.Geo.IsZero() {
+		p.GeoInfo = r.Geo
+	}
+	p.LastCheck = time.Now()
+}
+
+// classifyError 将网络错误归类，便于统计代理失败的原因
+func classifyError(err error) string {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.As(err, &dnsErr):
+		return ErrDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrReset
+	}
+
+	// 部分代理库不包装底层错误，只能根据错误信息判断
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "connection refused"):
+		return ErrRefused
+	case strings.Contains(msg, "connection reset"), strings.Contains(msg, "EOF"):
+		return ErrReset
+	case strings.Contains(msg, "Proxy Authentication Required"):
+		return ErrProxyAuth
+	}
+	return ErrOther
+}
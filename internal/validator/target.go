@@ -0,0 +1,93 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultTargetURL  = "http://ipinfo.io/json"
+	defaultConnectURL = "https://httpbin.org/ip" // 探测 CONNECT 隧道的默认地址
+	maxBodySize       = 1 << 20                  // 读取响应内容的上限
+)
+
+// Target 验证目标，代理请求目标后按状态码和响应内容判断是否通过
+type Target struct {
+	Name           string // 目标名称，记录在代理的 targets 中
+	URL            string // 请求地址，https 地址需要代理支持隧道
+	ExpectedStatus int    // 期望的状态码，默认 200
+	Contains       string // 响应内容需要包含的字符串，可选
+	JSONField      string // 响应 JSON 中需要存在且非空的字段，支持 a.b 形式的嵌套字段，可选
+}
+
+// DefaultTarget 返回默认验证目标，url 为空时使用 ipinfo.io 并检查返回的 ip 字段
+func DefaultTarget(url string) Target {
+	if url == "" {
+		return Target{Name: "default", URL: defaultTargetURL, ExpectedStatus: http.StatusOK, JSONField: "ip"}
+	}
+	return Target{Name: "default", URL: url, ExpectedStatus: http.StatusOK}
+}
+
+// NewTarget 创建验证目标并补全默认值
+func NewTarget(name, url string, expectedStatus int, contains, jsonField string) (Target, error) {
+	if url == "" {
+		return Target{}, fmt.Errorf("validation target %q has no url", name)
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return Target{}, fmt.Errorf("validation target %q has invalid url: %s", name, url)
+	}
+	if name == "" {
+		name = url
+	}
+	if expectedStatus == 0 {
+		expectedStatus = http.StatusOK
+	}
+	return Target{
+		Name:           name,
+		URL:            url,
+		ExpectedStatus: expectedStatus,
+		Contains:       contains,
+		JSONField:      jsonField,
+	}, nil
+}
+
+// HTTPS 目标是否为 https 地址
+func (t Target) HTTPS() bool {
+	return strings.HasPrefix(t.URL, "https://")
+}
+
+// match 检查响应内容是否满足目标的内容要求
+func (t Target) match(body []byte) bool {
+	if t.Contains != "" && !bytes.Contains(body, []byte(t.Contains)) {
+		return false
+	}
+	if t.JSONField == "" {
+		return true
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return false
+	}
+	for _, key := range strings.Split(t.JSONField, ".") {
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if data, ok = obj[key]; !ok {
+			return false
+		}
+	}
+
+	switch value := data.(type) {
+	case nil:
+		return false
+	case string:
+		return value != ""
+	default:
+		return true
+	}
+}
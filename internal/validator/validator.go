@@ -1,26 +1,36 @@
 package validator
 
 import (
+	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
+	"github.com/langchou/proxyPool/internal/dialer"
 	"github.com/langchou/proxyPool/internal/logger"
+	"github.com/langchou/proxyPool/internal/metrics"
 	"github.com/langchou/proxyPool/internal/model"
 
 	"go.uber.org/zap"
-	"golang.org/x/net/proxy"
 )
 
 type Validator struct {
-	timeout time.Duration
+	timeout    time.Duration
+	targets    []Target
+	judgeURL   string // 用于检测匿名级别的判定接口
+	connectURL string // 用于探测 CONNECT 隧道的 https 地址
+
+	mu       sync.Mutex
+	realIP   string    // 本机出口 IP，用于识别透明代理
+	realIPAt time.Time // 上一次获取本机出口 IP 的时间
 }
 
-// IPInfo 响应结构
+// IPInfo ipinfo 风格的响应结构，验证目标返回这种格式时从中获取出口 IP 的地理位置
 type IPInfo struct {
 	IP       string `json:"ip"`
 	Hostname string `json:"hostname"`
@@ -30,11 +40,45 @@ type IPInfo struct {
 	Org      string `json:"org"`
 }
 
-func NewValidator(timeout time.Duration) *Validator {
-	return &Validator{timeout: timeout}
+// Options 验证器参数
+type Options struct {
+	Timeout    time.Duration // 单次请求的超时时间
+	Targets    []Target      // 验证目标，为空时使用默认验证目标
+	JudgeURL   string        // 检测匿名级别的判定接口，为空时使用 httpbin
+	ConnectURL string        // 探测 CONNECT 隧道的 https 地址，为空时使用 httpbin
+}
+
+func NewValidator(options Options) *Validator {
+	if len(options.Targets) == 0 {
+		options.Targets = []Target{DefaultTarget("")}
+	}
+	if options.JudgeURL == "" {
+		options.JudgeURL = defaultJudgeURL
+	}
+	if options.ConnectURL == "" {
+		options.ConnectURL = defaultConnectURL
+	}
+	return &Validator{
+		timeout:    options.Timeout,
+		targets:    options.Targets,
+		judgeURL:   options.JudgeURL,
+		connectURL: options.ConnectURL,
+	}
+}
+
+// Check 验证代理并返回详细结果，失败时包含失败原因分类
+func (v *Validator) Check(p *model.Proxy) Result {
+	result := v.check(p)
+
+	label := "valid"
+	if !result.Valid {
+		label = result.Error
+	}
+	metrics.Validations.WithLabelValues(string(p.Type), label).Inc()
+	return result
 }
 
-func (v *Validator) Validate(p *model.Proxy) (bool, int64) {
+func (v *Validator) check(p *model.Proxy) Result {
 	logger.Log.Debug("Validating proxy",
 		zap.String("ip", p.IP),
 		zap.String("port", p.Port),
@@ -50,59 +94,143 @@ func (v *Validator) Validate(p *model.Proxy) (bool, int64) {
 		client, err = v.createSocksClient(p)
 	default:
 		logger.Log.Warn("Invalid proxy type", zap.String("type", string(p.Type)))
-		return false, 0
+		return Result{Error: ErrUnsupported}
 	}
 
 	if err != nil {
 		logger.Log.Error("Failed to create HTTP client", zap.Error(err))
-		return false, 0
+		return Result{Error: ErrUnsupported}
+	}
+
+	// 依次验证所有目标，记录代理通过了哪些目标
+	var result Result
+	var infoIP string
+	var hasHTTPS, passedHTTP, passedHTTPS bool
+	for _, target := range v.targets {
+		if target.HTTPS() {
+			hasHTTPS = true
+		}
+
+		speed, body, errClass := v.checkTarget(client, p, target)
+		if errClass == "" {
+			if !result.Valid {
+				result.Speed = speed
+			}
+			// 验证目标返回 ipinfo 风格的数据时记录出口 IP 的地理位置
+			if result.Geo.IsZero() {
+				if ip, geo, ok := parseIPInfo(body); ok {
+					infoIP = ip
+					result.Geo = geo
+				}
+			}
+			result.Valid = true
+			result.Targets = append(result.Targets, target.Name)
+			if target.HTTPS() {
+				passedHTTPS = true
+			} else {
+				passedHTTP = true
+			}
+			continue
+		}
+
+		if result.Error == "" {
+			result.Error = errClass
+		}
+		// 尚未通过任何目标时连接被拒绝，说明代理本身不可用，不必再验证其他目标
+		if errClass == ErrRefused && !result.Valid {
+			return result
+		}
+	}
+	// 验证未通过的代理不会保存检测结果，不再检测匿名级别和探测转发方式
+	if !result.Valid {
+		return result
+	}
+	result.Error = ""
+
+	// 通过代理请求判定接口检测匿名级别和出口 IP，判定接口为 http 地址，走的是普通转发，
+	// 判定成功也说明 HTTP 代理支持普通转发
+	isHTTPProxy := p.Type == model.ProxyTypeHTTP || p.Type == model.ProxyTypeHTTPS
+	var judged bool
+	result.Anonymity, result.ExitIP, judged = v.detectAnonymity(client, p)
+	if result.ExitIP == "" {
+		result.ExitIP = infoIP
 	}
 
+	// HTTP 代理分别记录是否支持普通转发和 CONNECT 隧道，验证目标已经覆盖的协议不再重复探测
+	if isHTTPProxy {
+		result.SupportsHTTP = passedHTTP || judged
+		result.SupportsConnect = passedHTTPS || (!hasHTTPS && v.probe(client, v.connectURL))
+	}
+	return result
+}
+
+// probe 通过代理请求指定地址，收到响应即视为成功，用于探测代理是否支持某种转发方式
+func (v *Validator) probe(client *http.Client, url string) bool {
+	resp, err := client.Get(url)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// checkTarget 通过代理请求验证目标，返回响应速度（毫秒）和失败原因，通过时失败原因为空
+func (v *Validator) checkTarget(client *http.Client, p *model.Proxy, target Target) (int64, []byte, string) {
 	start := time.Now()
 
-	resp, err := client.Get("http://ipinfo.io/json")
+	resp, err := client.Get(target.URL)
 	if err != nil {
 		logger.Log.Debug("Proxy validation failed",
 			zap.String("ip", p.IP),
+			zap.String("target", target.Name),
 			zap.Error(err))
-		return false, 0
+		return 0, nil, classifyError(err)
 	}
 	defer resp.Body.Close()
 
-	speed := time.Since(start).Milliseconds()
+	elapsed := time.Since(start)
+	speed := elapsed.Milliseconds()
 	logger.Log.Debug("Proxy response time",
 		zap.String("ip", p.IP),
+		zap.String("target", target.Name),
 		zap.Int64("speed_ms", speed))
 
-	if resp.StatusCode != http.StatusOK {
-		logger.Log.Debug("Proxy returned non-200 status",
+	if resp.StatusCode != target.ExpectedStatus {
+		logger.Log.Debug("Proxy returned unexpected status",
 			zap.String("ip", p.IP),
+			zap.String("target", target.Name),
 			zap.Int("status", resp.StatusCode))
-		return false, speed
-	}
-
-	// 读取并解析响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return false, speed
+		if resp.StatusCode == http.StatusProxyAuthRequired {
+			return speed, nil, ErrProxyAuth
+		}
+		return speed, nil, ErrBadStatus
 	}
 
-	var ipInfo IPInfo
-	if err := json.Unmarshal(body, &ipInfo); err != nil {
-		return false, speed
+	// 读取并检查响应内容，没有内容检查时只验证状态码，读取失败也不影响结果
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if target.Contains != "" || target.JSONField != "" {
+		if err != nil {
+			return speed, nil, classifyError(err)
+		}
+		if !target.match(body) {
+			return speed, nil, ErrBadResponse
+		}
 	}
-
-	// 验证返回的IP是否与代理IP匹配
-	// 注意：某些代理可能会返回不同的IP（级联代理），所以这里只验证是否成功获取到了IP信息
-	return ipInfo.IP != "", speed
+	metrics.ValidationLatency.WithLabelValues(string(p.Type), target.Name).Observe(elapsed.Seconds())
+	return speed, body, ""
 }
 
 func (v *Validator) createHTTPClient(p *model.Proxy) (*http.Client, error) {
-	proxyURL := fmt.Sprintf("%s://%s:%s", p.Type, p.IP, p.Port)
+	// https 类型的代理同样使用 http 协议连接代理本身，访问 https 地址时由 CONNECT 建立隧道
+	proxyURL := fmt.Sprintf("http://%s:%s", p.IP, p.Port)
 	parsedURL, err := url.Parse(proxyURL)
 	if err != nil {
 		return nil, err
 	}
+	// 代理带有账号时由 Transport 发送 Proxy-Authorization 基本认证
+	if p.Username != "" {
+		parsedURL.User = url.UserPassword(p.Username, p.Password)
+	}
 
 	// 创建跳过证书验证的 Transport
 	transport := &http.Transport{
@@ -119,14 +247,11 @@ func (v *Validator) createHTTPClient(p *model.Proxy) (*http.Client, error) {
 }
 
 func (v *Validator) createSocksClient(p *model.Proxy) (*http.Client, error) {
-	dialer, err := proxy.SOCKS5("tcp", p.IP+":"+p.Port, nil, proxy.Direct)
-	if err != nil {
-		return nil, err
-	}
-
-	// 创建跳过证书验证的 Transport
+	// 创建跳过证书验证的 Transport，连接通过 SOCKS4/4a 或 SOCKS5 握手建立
 	transport := &http.Transport{
-		Dial: dialer.Dial,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(ctx, p, addr, v.timeout)
+		},
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true, // 跳过证书验证
 		},